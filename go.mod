@@ -0,0 +1,8 @@
+module github.com/jakebowkett/go-steg
+
+go 1.22
+
+require (
+	golang.org/x/crypto v0.33.0
+	golang.org/x/image v0.24.0
+)
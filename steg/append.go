@@ -0,0 +1,35 @@
+package steg
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+Append writes msg to the image at src from prevEnd, the end
+point returned when an earlier message was encoded, and saves
+the result to dst, returning the new end point. Every pixel
+before prevEnd is left as it was, so a series of messages can be
+added to the same image one after another, each decoded from the
+previous end point to its own.
+
+When the encoder adds nothing to messages, meaning no magic
+marker, length header, checksum, compression, encryption, format
+header or null terminator, and every message fills the last
+pixel it is written to, Decode from the start of the first
+message to the last end point returns all of them joined
+together. With one channel and a bit depth of 1 every message
+fills its last pixel.
+
+Append returns an error wrapping ErrStartOutOfBounds if prevEnd
+is outside the bounds of src, and one wrapping ErrMsgTooLarge if
+msg doesn't fit between prevEnd and the end of the image. It
+otherwise behaves as Encode does.
+*/
+func (e *Encoder) Append(src, dst, msg string, prevEnd Point) (end Point, err error) {
+	end, err = e.Encode(src, dst, msg, prevEnd)
+	if errors.Is(err, ErrStartOutOfBounds) {
+		err = fmt.Errorf("prevEnd %v: %w", prevEnd, err)
+	}
+	return end, err
+}
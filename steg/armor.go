@@ -0,0 +1,107 @@
+package steg
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"fmt"
+)
+
+/*
+Armor specifies a text encoding applied to messages before they
+are written so that the bytes embedded are all printable.
+*/
+type Armor int
+
+const (
+	// ArmorNone embeds messages as they are.
+	ArmorNone Armor = iota
+
+	// ArmorBase64 embeds messages as padded standard base64,
+	// taking up 4 bytes for every 3.
+	ArmorBase64
+
+	// ArmorBase32 embeds messages as padded standard base32,
+	// taking up 8 bytes for every 5.
+	ArmorBase32
+)
+
+func (a Armor) valid() bool {
+	return a >= ArmorNone && a <= ArmorBase32
+}
+
+func (a Armor) String() string {
+	switch a {
+	case ArmorNone:
+		return "none"
+	case ArmorBase64:
+		return "base64"
+	case ArmorBase32:
+		return "base32"
+	}
+	return fmt.Sprintf("Armor(%d)", int(a))
+}
+
+/*
+SetArmor specifies an encoding that messages are converted to
+before being embedded, for images whose message bits pass through
+tooling that only handles printable text. The armor is applied
+after compression, encryption and the checksum, so all of the
+message's bytes are printable whatever other settings are used;
+only the magic marker and length header are left as they are.
+Decoding reverses the armor and returns an error if the decoded
+text isn't valid in its encoding. Messages must be decoded with
+the same armor. It takes up about a third more space for base64
+and more than half again for base32, which Capacity and the other
+functions that work out the size of a message take into account.
+
+If a is not a valid Armor SetArmor returns an error. By default
+no armor is used.
+*/
+func (e *Encoder) SetArmor(a Armor) error {
+	if !a.valid() {
+		return fmt.Errorf("invalid armor: got %d", a)
+	}
+	e.armor = a
+	return nil
+}
+
+// armoredSize returns how many bytes n bytes take up once
+// armored.
+func (e *Encoder) armoredSize(n int) int {
+	switch e.armor {
+	case ArmorBase64:
+		return base64.StdEncoding.EncodedLen(n)
+	case ArmorBase32:
+		return base32.StdEncoding.EncodedLen(n)
+	}
+	return n
+}
+
+// armorMsg applies the encoder's armor to msg.
+func (e *Encoder) armorMsg(msg []byte) []byte {
+	switch e.armor {
+	case ArmorBase64:
+		return base64.StdEncoding.AppendEncode(nil, msg)
+	case ArmorBase32:
+		return base32.StdEncoding.AppendEncode(nil, msg)
+	}
+	return msg
+}
+
+// unarmorMsg reverses armorMsg.
+func (e *Encoder) unarmorMsg(data []byte) ([]byte, error) {
+	var b []byte
+	var err error
+	switch e.armor {
+	case ArmorBase64:
+		b, err = base64.StdEncoding.AppendDecode(nil, data)
+	case ArmorBase32:
+		b, err = base32.StdEncoding.AppendDecode(nil, data)
+	default:
+		return data, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("malformed %s armor: %w", e.armor, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,194 @@
+package steg
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"sort"
+)
+
+// BatchItem is a single message written by EncodeBatch.
+type BatchItem struct {
+	Msg   string
+	Start Point
+}
+
+/*
+EncodeBatch writes the message of each item to src beginning at
+its Start point and saves the result to dst. Unlike calling
+Encode once per item src is only read and dst only written once.
+The returned end points are in the same order as items and are
+what Encode would have returned for each item.
+
+Every item is checked before any pixels are changed. If a
+message does not fit an error is returned and dst is not
+created. The same goes for items whose pixels would overlap
+those of another item, in which case the error wraps ErrOverlap
+and names the items.
+*/
+func (e *Encoder) EncodeBatch(src, dst string, items []BatchItem) (ends []Point, err error) {
+	_, err = e.encodeFile(src, dst, func(img draw.Image) (Point, error) {
+		ends, err = e.encodeBatch(img, items)
+		return Point{}, err
+	})
+	return ends, err
+}
+
+func (e *Encoder) encodeBatch(img draw.Image, items []BatchItem) (ends []Point, err error) {
+
+	c, err := e.carrierFor(img)
+	if err != nil {
+		return nil, err
+	}
+	if err = alphaCheck(img, e.activeChannels()); err != nil {
+		return nil, err
+	}
+
+	ps := make([]placement, len(items))
+	for i, item := range items {
+		if ps[i], err = e.place(c, []byte(item.Msg), item.Start); err != nil {
+			return nil, fmt.Errorf("batch item %d: %w", i, err)
+		}
+	}
+
+	if err = e.checkOverlap(c, items, ps); err != nil {
+		return nil, err
+	}
+
+	ends = make([]Point, len(items))
+	for i, p := range ps {
+		if _, err = e.write(context.Background(), c, p); err != nil {
+			return nil, err
+		}
+		ends[i] = p.end
+	}
+
+	return ends, nil
+}
+
+/*
+checkOverlap returns an error if any two placements share a
+pixel. A message only ever touches pixels between its start and
+end in traversal order so comparing those spans is enough.
+*/
+func (e *Encoder) checkOverlap(c carrier, items []BatchItem, ps []placement) error {
+
+	type span struct {
+		item, first, last int
+	}
+
+	bounds := c.bounds()
+	spans := make([]span, len(ps))
+	for i, p := range ps {
+		spans[i] = span{
+			item:  i,
+			first: offsetFromMin(bounds, e.traversal, items[i].Start),
+			last:  offsetFromMin(bounds, e.traversal, p.end),
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].first < spans[j].first
+	})
+
+	for i := 1; i < len(spans); i++ {
+		if prev := spans[i-1]; spans[i].first < prev.last {
+			return fmt.Errorf("%w: batch items %d and %d", ErrOverlap, prev.item, spans[i].item)
+		}
+	}
+
+	return nil
+}
+
+/*
+RegionFor returns the smallest rectangle containing every pixel
+of src that a message of msgLen bytes beginning at start would
+be written to, given the encoder's current settings. Messages
+whose rectangles do not overlap cannot collide, though ones
+whose rectangles do overlap may still use separate pixels when
+they share a row (or a column with TraversalColumnMajor); with
+TraversalHilbert the rectangle is found by visiting each pixel.
+
+When compression is enabled the size of the message once
+written is not known in advance so the rectangle is for the
+largest size it could be.
+*/
+func (e *Encoder) RegionFor(src string, start Point, msgLen int) (image.Rectangle, error) {
+
+	bounds, err := readBounds(src)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+
+	return e.regionForLen(bounds, start, msgLen)
+}
+
+func (e *Encoder) regionForLen(bounds image.Rectangle, start Point, msgLen int) (image.Rectangle, error) {
+
+	if msgLen <= 0 {
+		return image.Rectangle{}, ErrMsgEmpty
+	}
+	if err := e.checkSettings(); err != nil {
+		return image.Rectangle{}, err
+	}
+	if !inBounds(bounds, start) {
+		return image.Rectangle{}, ErrStartOutOfBounds
+	}
+
+	size, hdr := e.framedSize(msgLen)
+	pixels := e.payloadPixels(size, hdr)
+	if err := e.checkFits(bounds, start, e.formatPixels()+pixels); err != nil {
+		return image.Rectangle{}, err
+	}
+
+	body := e.bodyStart(bounds, start)
+	set := e.pixels(nil, bounds, body)
+	if set != nil {
+		if err := checkSetFits(set, pixels); err != nil {
+			return image.Rectangle{}, err
+		}
+	}
+
+	_, end, err := e.walk(bounds, body, set, hdr, pixels)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	if !inBounds(bounds, end) {
+		return image.Rectangle{}, ErrEndOutOfBounds
+	}
+
+	first := offsetFromMin(bounds, e.traversal, start)
+	last := offsetFromMin(bounds, e.traversal, end) - 1
+
+	return spanRect(bounds, e.traversal, first, last), nil
+}
+
+// spanRect returns the smallest rectangle containing the pixels
+// from offset first to last inclusive when visited in order t.
+func spanRect(bounds image.Rectangle, t Traversal, first, last int) image.Rectangle {
+
+	if t == TraversalHilbert {
+		var r image.Rectangle
+		for i := first; i <= last; i++ {
+			p := pointAt(bounds, t, i)
+			r = r.Union(image.Rect(p.X, p.Y, p.X+1, p.Y+1))
+		}
+		return r
+	}
+
+	a := pointAt(bounds, t, first)
+	b := pointAt(bounds, t, last)
+
+	if t == TraversalColumnMajor {
+		if a.X == b.X {
+			return image.Rect(a.X, a.Y, a.X+1, b.Y+1)
+		}
+		return image.Rect(a.X, bounds.Min.Y, b.X+1, bounds.Max.Y)
+	}
+
+	if a.Y == b.Y {
+		return image.Rect(a.X, a.Y, b.X+1, a.Y+1)
+	}
+	return image.Rect(bounds.Min.X, a.Y, bounds.Max.X, b.Y+1)
+}
@@ -0,0 +1,166 @@
+package steg
+
+import (
+	"errors"
+	"image"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEncodeBatch(t *testing.T) {
+
+	e, err := NewEncoder(WithLengthHeader(), WithChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := []BatchItem{
+		{"first", Point{0, 0}},
+		{"third, at the bottom", Point{0, 20}},
+		{"second", Point{4, 8}},
+	}
+
+	src := writePNG(t, noisyNRGBA(32, 32))
+	dst := dstPath(t, ".png")
+	ends, err := e.EncodeBatch(src, dst, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ends) != len(items) {
+		t.Fatalf("got %d end points, want %d", len(ends), len(items))
+	}
+
+	for i, item := range items {
+
+		// Each end point is the one Encode returns.
+		want, err := e.Encode(src, dstPath(t, ".png"), item.Msg, item.Start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ends[i] != want {
+			t.Fatalf("item %d ends at %v, want %v", i, ends[i], want)
+		}
+
+		got, err := e.Decode(dst, item.Start, ends[i])
+		if err != nil {
+			t.Fatalf("item %d: %v", i, err)
+		}
+		if got != item.Msg {
+			t.Fatalf("item %d: got %q, want %q", i, got, item.Msg)
+		}
+	}
+
+	dst = dstPath(t, ".png")
+	items = append(items, BatchItem{string(make([]byte, 200)), Point{0, 30}})
+	if _, err = e.EncodeBatch(src, dst, items); err == nil {
+		t.Fatal("EncodeBatch accepted an item that doesn't fit")
+	}
+	if _, err = os.Stat(dst); !os.IsNotExist(err) {
+		t.Fatalf("dst was saved despite the error: %v", err)
+	}
+}
+
+func TestEncodeBatchOverlap(t *testing.T) {
+
+	e, err := NewEncoder()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := writePNG(t, noisyNRGBA(32, 32))
+	dst := dstPath(t, ".png")
+
+	// The first message takes up 24 pixels, so the third one
+	// begins inside it.
+	_, err = e.EncodeBatch(src, dst, []BatchItem{
+		{"abc", Point{0, 0}},
+		{"def", Point{0, 10}},
+		{"ghi", Point{23, 0}},
+	})
+	if !errors.Is(err, ErrOverlap) || !strings.Contains(err.Error(), "items 0 and 2") {
+		t.Fatalf("got %v, want ErrOverlap naming items 0 and 2", err)
+	}
+	if _, err = os.Stat(dst); !os.IsNotExist(err) {
+		t.Fatalf("dst was saved despite the error: %v", err)
+	}
+
+	// Beginning just after it is fine.
+	if _, err = e.EncodeBatch(src, dst, []BatchItem{{"abc", Point{0, 0}}, {"ghi", Point{24, 0}}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegionFor(t *testing.T) {
+
+	src := writePNG(t, noisyNRGBA(32, 32))
+
+	for _, tt := range []struct {
+		traversal Traversal
+		start     Point
+		msgLen    int
+		want      image.Rectangle
+	}{
+		{TraversalRowMajor, Point{2, 3}, 3, image.Rect(2, 3, 26, 4)},
+		{TraversalRowMajor, Point{20, 3}, 3, image.Rect(0, 3, 32, 5)},
+		{TraversalColumnMajor, Point{5, 1}, 2, image.Rect(5, 1, 6, 17)},
+		{TraversalColumnMajor, Point{5, 20}, 2, image.Rect(5, 0, 7, 32)},
+	} {
+
+		e, err := NewEncoder(WithTraversal(tt.traversal))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := e.RegionFor(src, tt.start, tt.msgLen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r != tt.want {
+			t.Fatalf("traversal %d from %v: got %v, want %v", tt.traversal, tt.start, r, tt.want)
+		}
+	}
+
+	// Every pixel a message changes lies in its region, however
+	// the pixels are visited.
+	for _, opts := range [][]Option{
+		{WithTraversal(TraversalHilbert), WithLengthHeader()},
+		{WithScatterSeed(3), WithLengthHeader()},
+		{WithPixelStride(3, 2)},
+	} {
+
+		e, err := NewEncoder(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		const msg = "inside the region"
+		start := Point{7, 9}
+		r, err := e.RegionFor(src, start, len(msg))
+		if err != nil {
+			t.Fatalf("%s: %v", e, err)
+		}
+
+		orig := noisyNRGBA(32, 32)
+		img := copyNRGBA(orig)
+		if _, err = e.EncodeImage(img, msg, start); err != nil {
+			t.Fatalf("%s: %v", e, err)
+		}
+		for _, i := range changedPixels(orig, img) {
+			if p := (image.Point{i % 32, i / 32}); !p.In(r) {
+				t.Fatalf("%s: pixel %v changed outside the region %v", e, p, r)
+			}
+		}
+	}
+
+	e, err := NewEncoder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.RegionFor(src, Point{0, 0}, 200); !errors.Is(err, ErrMsgTooLarge) {
+		t.Fatalf("got %v, want ErrMsgTooLarge", err)
+	}
+	if _, err = e.RegionFor(src, Point{40, 0}, 1); !errors.Is(err, ErrStartOutOfBounds) {
+		t.Fatalf("got %v, want ErrStartOutOfBounds", err)
+	}
+}
@@ -0,0 +1,60 @@
+package steg
+
+import "fmt"
+
+/*
+BitOrder specifies the order in which the bits of each message
+byte are written to and read from the image.
+*/
+type BitOrder int
+
+const (
+	// MSBFirst writes the most significant bit of each byte
+	// first.
+	MSBFirst BitOrder = iota
+
+	// LSBFirst writes the least significant bit of each byte
+	// first, as many other steganography tools do.
+	LSBFirst
+)
+
+func (o BitOrder) valid() bool {
+	return o == MSBFirst || o == LSBFirst
+}
+
+func (o BitOrder) String() string {
+	switch o {
+	case MSBFirst:
+		return "msb-first"
+	case LSBFirst:
+		return "lsb-first"
+	}
+	return fmt.Sprintf("BitOrder(%d)", int(o))
+}
+
+// shift returns the position within a byte of its i-th bit in
+// order o, with 0 being the least significant.
+func (o BitOrder) shift(i int) uint {
+	if o == LSBFirst {
+		return uint(i)
+	}
+	return uint(7 - i)
+}
+
+/*
+SetBitOrder specifies the order in which the bits of each byte
+of the message, including the magic marker and length header,
+are written, for compatibility with images produced or read by
+other tools. Messages must be decoded using the same bit order
+they were encoded with. The format header is always written
+most significant bit first and does not record the bit order.
+If order is not a valid BitOrder SetBitOrder returns an error.
+By default bytes are written most significant bit first.
+*/
+func (e *Encoder) SetBitOrder(order BitOrder) error {
+	if !order.valid() {
+		return fmt.Errorf("invalid bit order: got %d", order)
+	}
+	e.bitOrder = order
+	return nil
+}
@@ -0,0 +1,85 @@
+package steg
+
+import (
+	"context"
+	"errors"
+)
+
+/*
+DecodeBruteforce is like DecodeAuto but recovers messages whose
+channel and msg bit have been forgotten by trying every
+combination of the two, returning the message along with the
+bit and channel it was found in. Bits are tried from the least
+significant up, each in the red, green, blue and alpha channels
+in turn, so at most 16 bits of 4 channels are tried for images
+with 16-bit samples and 8 bits of 4 channels otherwise;
+combinations the image can't hold, such as the alpha channel of
+a greyscale image, are skipped. A combination is used once the
+magic marker is found at start and the message decodes. All
+other settings, including the bit depth, are those of e.
+
+The encoder must have a magic marker (see SetMagic), so that the
+message can be recognised, and the length header enabled (see
+SetLengthHeader), so that no end point is needed. If the marker
+is found but the message doesn't decode for any combination the
+first such error is returned, otherwise ErrNoMessage.
+*/
+func (e *Encoder) DecodeBruteforce(src string, start Point) (msg string, bit int, ch Channel, err error) {
+
+	if len(e.magic) == 0 {
+		return msg, bit, ch, errors.New("DecodeBruteforce requires a magic marker; see SetMagic")
+	}
+	if !e.lengthHeader {
+		return msg, bit, ch, errors.New("length header is not enabled")
+	}
+
+	img, err := readImage(src)
+	if err != nil {
+		return msg, bit, ch, err
+	}
+
+	c, err := e.carrierFor(img)
+	if err != nil {
+		return msg, bit, ch, err
+	}
+	if !inBounds(c.bounds(), start) {
+		return msg, bit, ch, ErrStartOutOfBounds
+	}
+
+	ctx := context.Background()
+	var decodeErr error
+
+	for bit = 0; bit+e.bitDepth() <= c.sampleBits(); bit++ {
+		for ch = ChannelRed; ch <= ChannelAlpha; ch++ {
+
+			d := e.Clone()
+			if d.SetChannel(ch) != nil || d.SetMsgBit(bit) != nil {
+				continue
+			}
+
+			c, err := d.carrierFor(img)
+			if err != nil {
+				continue
+			}
+			if _, _, err := d.readHeader(ctx, c, start); err != nil {
+				continue
+			}
+
+			b, err := d.decodeAuto(ctx, c, start)
+			if err != nil {
+				if decodeErr == nil {
+					decodeErr = err
+				}
+				continue
+			}
+
+			return string(b), bit, ch, nil
+		}
+	}
+
+	if decodeErr != nil {
+		return "", 0, 0, decodeErr
+	}
+
+	return "", 0, 0, ErrNoMessage
+}
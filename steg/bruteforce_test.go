@@ -0,0 +1,78 @@
+package steg
+
+import (
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestDecodeBruteforce(t *testing.T) {
+
+	const msg = "forgotten where"
+	magic := WithMagic([]byte("BRUTE"))
+
+	for _, tt := range []struct {
+		img   image.Image
+		ch    Channel
+		bit   int
+		depth int
+	}{
+		{noisyNRGBA(24, 24), ChannelRed, 0, 1},
+		{noisyNRGBA(24, 24), ChannelBlue, 3, 1},
+		{noisyNRGBA(24, 24), ChannelGreen, 7, 1},
+		{noisyNRGBA(24, 24), ChannelAlpha, 1, 1},
+		{noisyNRGBA(24, 24), ChannelGreen, 5, 3},
+		{noisyDeep(24, 24)[0], ChannelRed, 12, 1},
+		{noisyDeep(24, 24)[1], ChannelBlue, 9, 2},
+	} {
+
+		e, err := NewEncoder(magic, WithLengthHeader(), WithChannel(tt.ch), WithBit(tt.bit), WithBitDepth(tt.depth))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dst := dstPath(t, ".png")
+		if _, err = e.Encode(writePNG(t, tt.img), dst, msg, Point{1, 1}); err != nil {
+			t.Fatalf("%s: %v", e, err)
+		}
+
+		// Only the depth is kept.
+		d, err := NewEncoder(magic, WithLengthHeader(), WithBitDepth(tt.depth))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, bit, ch, err := d.DecodeBruteforce(dst, Point{1, 1})
+		if err != nil {
+			t.Fatalf("%s: %v", e, err)
+		}
+		if got != msg || bit != tt.bit || ch != tt.ch {
+			t.Fatalf("%s: got %q in bit %d of channel %d, want %q in bit %d of channel %d", e, got, bit, ch, msg, tt.bit, tt.ch)
+		}
+	}
+}
+
+func TestDecodeBruteforceErrors(t *testing.T) {
+
+	src := writePNG(t, noisyNRGBA(16, 16))
+
+	e, err := NewEncoder(WithMagic([]byte("BRUTE")), WithLengthHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err = e.DecodeBruteforce(src, Point{}); !errors.Is(err, ErrNoMessage) {
+		t.Errorf("got %v from an image without a message, want ErrNoMessage", err)
+	}
+	if _, _, _, err = e.DecodeBruteforce(src, Point{16, 0}); !errors.Is(err, ErrStartOutOfBounds) {
+		t.Errorf("got %v, want ErrStartOutOfBounds", err)
+	}
+
+	for _, opts := range [][]Option{{WithLengthHeader()}, {WithMagic([]byte("BRUTE"))}} {
+		e, err := NewEncoder(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, _, err = e.DecodeBruteforce(src, Point{}); err == nil || errors.Is(err, ErrNoMessage) {
+			t.Errorf("%s: got %v, want a settings error", e, err)
+		}
+	}
+}
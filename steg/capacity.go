@@ -0,0 +1,142 @@
+package steg
+
+import (
+	"fmt"
+	"image"
+)
+
+/*
+CapacityInfo describes how much an image can hold from a given
+start point under an encoder's settings, as returned by
+Encoder.CapacityInfo.
+
+TotalPixels is the number of pixels in the image and Pixels the
+number of those from the start point that can hold message
+data. BitsPerPixel is how many bits are stored in each pixel and
+PixelsPerByte how many pixels each byte of message takes up,
+which is fractional when more than one channel or a bit depth
+above 1 is used. Bytes is how many bytes fit in Pixels, while
+Overhead is how many of those are taken by the encoder's magic
+marker, headers, checksum and so on, leaving MsgBytes, the same
+as Capacity returns, for the message itself.
+The length header grows with the message so Overhead is for the
+longest message that could fit, and when compression is enabled
+it is the most compression can add.
+*/
+type CapacityInfo struct {
+	TotalPixels   int
+	Pixels        int
+	BitsPerPixel  int
+	PixelsPerByte float64
+	Bytes         int
+	Overhead      int
+
+	enc Encoder
+}
+
+// Fits reports whether a message of msgLen bytes can be
+// encoded from the start point.
+func (c CapacityInfo) Fits(msgLen int) bool {
+	size, _ := c.enc.framedSize(msgLen)
+	return msgLen > 0 && size <= c.Bytes
+}
+
+// MsgBytes returns the length of the longest message that
+// Fits.
+func (c CapacityInfo) MsgBytes() int {
+	n := c.Bytes - c.Overhead
+	for c.Fits(n + 1) {
+		n++
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// msgBytes returns the length of the longest message whose
+// framed payload fits in n bytes.
+func (e *Encoder) msgBytes(n int) int {
+	size, _ := e.framedSize(n)
+	return CapacityInfo{Bytes: n, Overhead: size - n, enc: *e}.MsgBytes()
+}
+
+/*
+CapacityInfo is like Capacity but returns a fuller description
+of the capacity of the image at src from start.
+*/
+func (e *Encoder) CapacityInfo(src string, start Point) (CapacityInfo, error) {
+
+	bounds, err := readBounds(src)
+	if err != nil {
+		return CapacityInfo{}, err
+	}
+
+	return e.capacityInfo(bounds, start)
+}
+
+/*
+CapacityInfoImage is like CapacityInfo but takes an already
+decoded image.
+*/
+func (e *Encoder) CapacityInfoImage(img image.Image, start Point) (CapacityInfo, error) {
+	if err := checkImage(img); err != nil {
+		return CapacityInfo{}, err
+	}
+	return e.capacityInfo(img.Bounds(), start)
+}
+
+func (e *Encoder) capacityInfo(bounds image.Rectangle, start Point) (CapacityInfo, error) {
+
+	n, err := e.capacity(bounds, start)
+	if err != nil {
+		return CapacityInfo{}, err
+	}
+
+	total := bounds.Dx() * bounds.Dy()
+	bpp := e.bitsPerPixel()
+	size, _ := e.framedSize(n)
+
+	return CapacityInfo{
+		TotalPixels:   total,
+		Pixels:        total - offsetFromMin(bounds, e.traversal, start) - 1,
+		BitsPerPixel:  bpp,
+		PixelsPerByte: float64(e.byteBits()) / float64(bpp),
+		Bytes:         n,
+		Overhead:      size - n,
+		enc:           *e,
+	}, nil
+}
+
+/*
+checkFits returns an error wrapping ErrMsgTooLarge if pixels
+can't be written from start, given that the last pixel of
+bounds is never written to. The error says how large the image
+would need to be, growing it along the direction of traversal,
+except with TraversalHilbert where the curve depends on the size
+of the image and only the shortfall is given.
+*/
+func (e *Encoder) checkFits(bounds image.Rectangle, start Point, pixels int) error {
+
+	first := offsetFromMin(bounds, e.traversal, start)
+	avail := bounds.Dx()*bounds.Dy() - first - 1
+
+	if pixels <= avail {
+		return nil
+	}
+
+	if e.traversal == TraversalHilbert {
+		return fmt.Errorf("%w: %w: msg needs %d pixels but only %d are available from start, %d short",
+			ErrMsgTooLarge, ErrEndOutOfBounds, pixels, avail, pixels-avail)
+	}
+
+	w, h := bounds.Dx(), bounds.Dy()
+	if e.traversal == TraversalColumnMajor {
+		w = (first+pixels)/h + 1
+	} else {
+		h = (first+pixels)/w + 1
+	}
+
+	return fmt.Errorf("%w: %w: msg needs %d pixels but only %d are available from start, %d short; the image would need to be %dx%d",
+		ErrMsgTooLarge, ErrEndOutOfBounds, pixels, avail, pixels-avail, w, h)
+}
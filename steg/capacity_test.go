@@ -0,0 +1,45 @@
+package steg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCapacityLeavesOutFraming(t *testing.T) {
+
+	e, err := NewEncoder(WithMagic([]byte("MAGIC")), WithLengthHeader(), WithChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := writePNG(t, noisyNRGBA(40, 40))
+	start := Point{}
+
+	n, err := e.Capacity(src, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ci, err := e.CapacityInfo(src, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != ci.MsgBytes() {
+		t.Fatalf("Capacity is %d, MsgBytes is %d", n, ci.MsgBytes())
+	}
+
+	dst := dstPath(t, ".png")
+	end, err := e.Encode(src, dst, strings.Repeat("m", n), start)
+	if err != nil {
+		t.Fatalf("encoding %d bytes: %v", n, err)
+	}
+	got, err := e.Decode(dst, start, end)
+	if err != nil || len(got) != n {
+		t.Fatalf("decoded %d bytes, want %d: %v", len(got), n, err)
+	}
+
+	_, err = e.Encode(src, dst, strings.Repeat("m", n+1), start)
+	if !errors.Is(err, ErrMsgTooLarge) {
+		t.Fatalf("encoding %d bytes: got %v, want ErrMsgTooLarge", n+1, err)
+	}
+}
@@ -0,0 +1,291 @@
+package steg
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"reflect"
+)
+
+/*
+carrier gives access to the raw samples of an image that
+message bits are written to and read from. Samples are 8 or 16
+bits wide as reported by sampleBits; 8-bit samples only use the
+low byte of the values passed to and from sample and setSample.
+*/
+type carrier interface {
+	bounds() image.Rectangle
+	check(cs []Channel) error
+	sampleBits() int
+	sample(x, y int, c Channel) uint16
+	setSample(x, y int, c Channel, v uint16)
+}
+
+/*
+checkImage returns ErrEmptyImage if img is nil, including a nil
+pointer to one of the image types, or has no pixels.
+*/
+func checkImage(img image.Image) error {
+	if img == nil {
+		return fmt.Errorf("%w: image is nil", ErrEmptyImage)
+	}
+	if v := reflect.ValueOf(img); v.Kind() == reflect.Pointer && v.IsNil() {
+		return fmt.Errorf("%w: %T is nil", ErrEmptyImage, img)
+	}
+	return checkBounds(img.Bounds())
+}
+
+func checkBounds(r image.Rectangle) error {
+	if r.Dx() <= 0 || r.Dy() <= 0 {
+		return fmt.Errorf("%w: got %dx%d, wanted a positive width and height", ErrEmptyImage, r.Dx(), r.Dy())
+	}
+	return nil
+}
+
+func newCarrier(img image.Image) (carrier, error) {
+	switch img := img.(type) {
+	case *image.RGBA:
+		return rgbaCarrier{img.Pix, img.Stride, img.Rect}, nil
+	case *image.NRGBA:
+		return rgbaCarrier{img.Pix, img.Stride, img.Rect}, nil
+	case *image.Gray:
+		return grayCarrier{img}, nil
+	case *image.Paletted:
+		return palettedCarrier{img}, nil
+	case *image.RGBA64:
+		return rgba64Carrier{img.Pix, img.Stride, img.Rect}, nil
+	case *image.NRGBA64:
+		return rgba64Carrier{img.Pix, img.Stride, img.Rect}, nil
+	case *image.Gray16:
+		return gray16Carrier{img}, nil
+	}
+	return nil, fmt.Errorf("%w %T", ErrUnsupportedImage, img)
+}
+
+// regionCarrier confines a carrier to part of its image.
+type regionCarrier struct {
+	carrier
+	rect image.Rectangle
+}
+
+func (c regionCarrier) bounds() image.Rectangle {
+	return c.rect
+}
+
+// rgbaCarrier covers both *image.RGBA and *image.NRGBA
+// which share the same pixel layout.
+type rgbaCarrier struct {
+	pix    []uint8
+	stride int
+	rect   image.Rectangle
+}
+
+func (c rgbaCarrier) bounds() image.Rectangle {
+	return c.rect
+}
+
+func (c rgbaCarrier) check(cs []Channel) error {
+	return nil
+}
+
+func (c rgbaCarrier) sampleBits() int {
+	return 8
+}
+
+func (c rgbaCarrier) offset(x, y int, ch Channel) int {
+	return (y-c.rect.Min.Y)*c.stride + (x-c.rect.Min.X)*4 + int(ch)
+}
+
+func (c rgbaCarrier) sample(x, y int, ch Channel) uint16 {
+	return uint16(c.pix[c.offset(x, y, ch)])
+}
+
+func (c rgbaCarrier) setSample(x, y int, ch Channel, v uint16) {
+	c.pix[c.offset(x, y, ch)] = byte(v)
+}
+
+// rgba64Carrier covers both *image.RGBA64 and *image.NRGBA64,
+// whose samples are 16 bits wide and stored big-endian.
+type rgba64Carrier struct {
+	pix    []uint8
+	stride int
+	rect   image.Rectangle
+}
+
+func (c rgba64Carrier) bounds() image.Rectangle {
+	return c.rect
+}
+
+func (c rgba64Carrier) check(cs []Channel) error {
+	return nil
+}
+
+func (c rgba64Carrier) sampleBits() int {
+	return 16
+}
+
+func (c rgba64Carrier) offset(x, y int, ch Channel) int {
+	return (y-c.rect.Min.Y)*c.stride + (x-c.rect.Min.X)*8 + int(ch)*2
+}
+
+func (c rgba64Carrier) sample(x, y int, ch Channel) uint16 {
+	i := c.offset(x, y, ch)
+	return uint16(c.pix[i])<<8 | uint16(c.pix[i+1])
+}
+
+func (c rgba64Carrier) setSample(x, y int, ch Channel, v uint16) {
+	i := c.offset(x, y, ch)
+	c.pix[i], c.pix[i+1] = byte(v>>8), byte(v)
+}
+
+/*
+alphaCheck refuses to write to the alpha channel of
+premultiplied images. Their colour channels are scaled by
+alpha so changing alpha alone would change the colour of the
+pixel or leave it invalid. Reading is unaffected.
+*/
+func alphaCheck(img image.Image, cs []Channel) error {
+
+	switch img.(type) {
+	case *image.RGBA, *image.RGBA64:
+	default:
+		return nil
+	}
+
+	for _, c := range cs {
+		if c == ChannelAlpha {
+			return fmt.Errorf("%w: cannot write to the alpha channel of premultiplied %T; use a non-premultiplied image such as *image.NRGBA", ErrUnsupportedImage, img)
+		}
+	}
+
+	return nil
+}
+
+/*
+nonPremultiplied returns a non-premultiplied copy of img if it
+is premultiplied, otherwise img itself. The conversion is exact
+for opaque pixels, which are the only kind png.Decode returns
+premultiplied images for.
+*/
+func nonPremultiplied(img draw.Image) draw.Image {
+
+	var dst draw.Image
+	b := img.Bounds()
+
+	switch img.(type) {
+	case *image.RGBA:
+		dst = image.NewNRGBA(b)
+	case *image.RGBA64:
+		dst = image.NewNRGBA64(b)
+	default:
+		return img
+	}
+
+	draw.Draw(dst, b, img, b.Min, draw.Src)
+	return dst
+}
+
+// singleSampleCheck is used by images that only have one
+// sample per pixel. The sample is reached through any of the
+// colour channels but there is no alpha channel.
+func singleSampleCheck(img image.Image, cs []Channel) error {
+	if len(cs) > 1 {
+		return fmt.Errorf("image type %T has only one channel per pixel: got %d channels", img, len(cs))
+	}
+	if cs[0] == ChannelAlpha {
+		return fmt.Errorf("image type %T has no alpha channel", img)
+	}
+	return nil
+}
+
+type grayCarrier struct {
+	img *image.Gray
+}
+
+func (c grayCarrier) bounds() image.Rectangle {
+	return c.img.Rect
+}
+
+func (c grayCarrier) check(cs []Channel) error {
+	return singleSampleCheck(c.img, cs)
+}
+
+func (c grayCarrier) sampleBits() int {
+	return 8
+}
+
+func (c grayCarrier) sample(x, y int, ch Channel) uint16 {
+	return uint16(c.img.Pix[c.img.PixOffset(x, y)])
+}
+
+func (c grayCarrier) setSample(x, y int, ch Channel, v uint16) {
+	c.img.Pix[c.img.PixOffset(x, y)] = byte(v)
+}
+
+// palettedCarrier stores message bits in the palette index of
+// each pixel rather than in a colour channel.
+type palettedCarrier struct {
+	img *image.Paletted
+}
+
+func (c palettedCarrier) bounds() image.Rectangle {
+	return c.img.Rect
+}
+
+func (c palettedCarrier) check(cs []Channel) error {
+	if len(c.img.Palette) == 0 {
+		return errors.New("paletted image has an empty palette")
+	}
+	return singleSampleCheck(c.img, cs)
+}
+
+func (c palettedCarrier) sampleBits() int {
+	return 8
+}
+
+func (c palettedCarrier) sample(x, y int, ch Channel) uint16 {
+	return uint16(c.img.Pix[c.img.PixOffset(x, y)])
+}
+
+/*
+setSample grows the palette when v indexes past its end. The
+new entries repeat the pixel's original colour so the change
+of index is not visible.
+*/
+func (c palettedCarrier) setSample(x, y int, ch Channel, v uint16) {
+	i := c.img.PixOffset(x, y)
+	old := c.img.Palette[c.img.Pix[i]]
+	for int(v) >= len(c.img.Palette) {
+		c.img.Palette = append(c.img.Palette, old)
+	}
+	c.img.Pix[i] = byte(v)
+}
+
+// gray16Carrier exposes the full 16 bits of each sample in the
+// same way as rgba64Carrier.
+type gray16Carrier struct {
+	img *image.Gray16
+}
+
+func (c gray16Carrier) bounds() image.Rectangle {
+	return c.img.Rect
+}
+
+func (c gray16Carrier) check(cs []Channel) error {
+	return singleSampleCheck(c.img, cs)
+}
+
+func (c gray16Carrier) sampleBits() int {
+	return 16
+}
+
+func (c gray16Carrier) sample(x, y int, ch Channel) uint16 {
+	i := c.img.PixOffset(x, y)
+	return uint16(c.img.Pix[i])<<8 | uint16(c.img.Pix[i+1])
+}
+
+func (c gray16Carrier) setSample(x, y int, ch Channel, v uint16) {
+	i := c.img.PixOffset(x, y)
+	c.img.Pix[i], c.img.Pix[i+1] = byte(v>>8), byte(v)
+}
@@ -0,0 +1,255 @@
+package steg
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"math/rand"
+	"testing"
+)
+
+// noisyDeep returns w x h opaque 16-bit gray and colour images
+// filled with noise that is the same on every run.
+func noisyDeep(w, h int) []image.Image {
+	r := rand.New(rand.NewSource(1))
+	gray := image.NewGray16(image.Rect(0, 0, w, h))
+	r.Read(gray.Pix)
+	rgba := image.NewNRGBA64(image.Rect(0, 0, w, h))
+	r.Read(rgba.Pix)
+	for i := 6; i < len(rgba.Pix); i += 8 {
+		rgba.Pix[i], rgba.Pix[i+1] = 0xff, 0xff
+	}
+	return []image.Image{gray, rgba}
+}
+
+func TestEncode16Bit(t *testing.T) {
+
+	const msg = "sixteen bits per sample"
+
+	for _, bit := range []int{0, 7, 8, 12, 15} {
+		for _, img := range noisyDeep(32, 32) {
+
+			var e Encoder
+			e.SetLengthHeader(true)
+			if err := e.SetMsgBit(bit); err != nil {
+				t.Fatal(err)
+			}
+
+			src := writePNG(t, img)
+			dst := dstPath(t, ".png")
+			if _, err := e.Encode(src, dst, msg, Point{}); err != nil {
+				t.Fatalf("%T bit %d: %v", img, bit, err)
+			}
+
+			got, err := e.DecodeAuto(dst, Point{})
+			if err != nil {
+				t.Fatalf("%T bit %d: %v", img, bit, err)
+			}
+			if got != msg {
+				t.Fatalf("%T bit %d: got %q, want %q", img, bit, got, msg)
+			}
+
+			out, err := readImage(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before, err := newCarrier(img)
+			if err != nil {
+				t.Fatal(err)
+			}
+			after, err := newCarrier(out)
+			if err != nil {
+				t.Fatalf("%T bit %d: saved as %T: %v", img, bit, out, err)
+			}
+			if after.sampleBits() != 16 {
+				t.Fatalf("%T bit %d: saved as %T with %d bits per sample", img, bit, out, after.sampleBits())
+			}
+
+			// Only the msg bit of the red (or gray) sample may change.
+			b := img.Bounds()
+			mask := ^uint16(1 << bit)
+			changed := 0
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					for _, ch := range []Channel{ChannelRed, ChannelGreen, ChannelBlue} {
+						if _, ok := img.(*image.Gray16); ok && ch != ChannelRed {
+							continue
+						}
+						v0, v1 := before.sample(x, y, ch), after.sample(x, y, ch)
+						if v0 != v1 {
+							changed++
+						}
+						if ch != ChannelRed && v0 != v1 || v0&mask != v1&mask {
+							t.Fatalf("%T bit %d: channel %d at (%d, %d) went from %#04x to %#04x", img, bit, ch, x, y, v0, v1)
+						}
+					}
+				}
+			}
+			if changed == 0 {
+				t.Fatalf("%T bit %d: no samples changed", img, bit)
+			}
+		}
+	}
+}
+
+func TestEncodeHighBit8Bit(t *testing.T) {
+	var e Encoder
+	e.SetLengthHeader(true)
+	if err := e.SetMsgBit(8); err != nil {
+		t.Fatal(err)
+	}
+	img := noisyNRGBA(16, 16)
+	if _, err := e.EncodeImage(img, "hello", Point{}); err == nil {
+		t.Fatal("encoding bit 8 of an 8-bit image succeeded")
+	}
+	if _, err := e.DecodeAutoImage(img, Point{}); err == nil {
+		t.Fatal("decoding bit 8 of an 8-bit image succeeded")
+	}
+}
+
+func TestEncodeAlpha(t *testing.T) {
+
+	const msg = "hidden in the alpha channel"
+
+	var e Encoder
+	e.SetLengthHeader(true)
+	if err := e.SetChannel(ChannelAlpha); err != nil {
+		t.Fatal(err)
+	}
+
+	// An opaque PNG decodes as a premultiplied *image.RGBA, which
+	// Encode must convert before it can write to alpha.
+	opaque := noisyNRGBA(32, 32)
+	translucent := noisyNRGBA(32, 32)
+	for i := 3; i < len(translucent.Pix); i += 4 {
+		translucent.Pix[i] = byte(0x80 + i%0x7f)
+	}
+
+	for _, img := range []*image.NRGBA{opaque, translucent} {
+
+		src := writePNG(t, img)
+		dst := dstPath(t, ".png")
+		if _, err := e.Encode(src, dst, msg, Point{}); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := e.DecodeAuto(dst, Point{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != msg {
+			t.Fatalf("got %q, want %q", got, msg)
+		}
+
+		out, err := readImage(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nrgba, ok := out.(*image.NRGBA)
+		if !ok {
+			t.Fatalf("saved as %T, want *image.NRGBA", out)
+		}
+
+		alphaChanged := false
+		for i := range img.Pix {
+			if i%4 == 3 {
+				alphaChanged = alphaChanged || nrgba.Pix[i] != img.Pix[i]
+				continue
+			}
+			if nrgba.Pix[i] != img.Pix[i] {
+				t.Fatalf("colour sample %d went from %#02x to %#02x", i, img.Pix[i], nrgba.Pix[i])
+			}
+		}
+		if !alphaChanged {
+			t.Fatal("no alpha samples changed")
+		}
+	}
+}
+
+func TestEncodeImageAlphaPremultiplied(t *testing.T) {
+
+	var e Encoder
+	e.SetLengthHeader(true)
+	if err := e.SetChannel(ChannelAlpha); err != nil {
+		t.Fatal(err)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	copy(img.Pix, noisyNRGBA(32, 32).Pix)
+	orig := append([]byte(nil), img.Pix...)
+
+	_, err := e.EncodeImage(img, "hello", Point{})
+	if !errors.Is(err, ErrUnsupportedImage) {
+		t.Fatalf("got error %v, want ErrUnsupportedImage", err)
+	}
+	if string(img.Pix) != string(orig) {
+		t.Fatal("image was changed by a failed encode")
+	}
+}
+
+func TestDegenerateImages(t *testing.T) {
+
+	var e Encoder
+	e.SetLengthHeader(true)
+
+	for _, img := range []draw.Image{
+		nil,
+		(*image.RGBA)(nil),
+		(*image.Gray16)(nil),
+		image.NewRGBA(image.Rect(0, 0, 0, 5)),
+		image.NewNRGBA(image.Rect(0, 0, 5, 0)),
+		image.NewGray(image.Rect(3, 3, 3, 3)),
+	} {
+		if _, err := e.EncodeImage(img, "hello", Point{}); !errors.Is(err, ErrEmptyImage) {
+			t.Errorf("EncodeImage(%T): got error %v, want ErrEmptyImage", img, err)
+		}
+		if _, err := e.DecodeImage(img, Point{}, Point{1, 1}); !errors.Is(err, ErrEmptyImage) {
+			t.Errorf("DecodeImage(%T): got error %v, want ErrEmptyImage", img, err)
+		}
+		if _, err := e.DecodeAutoImage(img, Point{}); !errors.Is(err, ErrEmptyImage) {
+			t.Errorf("DecodeAutoImage(%T): got error %v, want ErrEmptyImage", img, err)
+		}
+		if _, err := e.CapacityImage(img, Point{}); !errors.Is(err, ErrEmptyImage) {
+			t.Errorf("CapacityImage(%T): got error %v, want ErrEmptyImage", img, err)
+		}
+	}
+
+	for _, r := range []image.Rectangle{
+		image.Rect(0, 0, 0, 5),
+		image.Rect(2, 2, 9, 2),
+		{},
+	} {
+		if p := PointAt(r, 0); inBounds(r, p) {
+			t.Errorf("PointAt(%v, 0) = %v, which is inside an empty rectangle", r, p)
+		}
+		if i := PixelIndex(r, Point(r.Min)); i != -1 {
+			t.Errorf("PixelIndex(%v, %v) = %d, want -1", r, r.Min, i)
+		}
+	}
+
+	// A single pixel can't hold even a byte.
+	one := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	if _, err := e.EncodeImage(one, "h", Point{}); !errors.Is(err, ErrMsgTooLarge) {
+		t.Errorf("EncodeImage(1x1): got error %v, want ErrMsgTooLarge", err)
+	}
+	if _, err := e.DecodeImage(one, Point{}, Point{}); !errors.Is(err, ErrStartAfterEnd) {
+		t.Errorf("DecodeImage(1x1): got error %v, want ErrStartAfterEnd", err)
+	}
+	if _, err := e.DecodeAutoImage(one, Point{}); err == nil {
+		t.Error("DecodeAutoImage(1x1) succeeded")
+	}
+	if n, err := e.CapacityImage(one, Point{}); err != nil || n != 0 {
+		t.Errorf("CapacityImage(1x1) = %d, %v, want 0", n, err)
+	}
+	if p := PointAt(one.Rect, 0); p != (Point{}) {
+		t.Errorf("PointAt(1x1, 0) = %v, want (0, 0)", p)
+	}
+
+	src := writePNG(t, one)
+	if _, err := e.Encode(src, dstPath(t, ".png"), "h", Point{}); !errors.Is(err, ErrMsgTooLarge) {
+		t.Errorf("Encode(1x1): got error %v, want ErrMsgTooLarge", err)
+	}
+	if _, err := e.DecodeAuto(src, Point{}); err == nil {
+		t.Error("DecodeAuto(1x1) succeeded")
+	}
+}
@@ -0,0 +1,89 @@
+package steg
+
+import (
+	"fmt"
+	"image"
+)
+
+/*
+ChiSquareLSB returns the chi-square statistic of bit plane bit
+of the image at src, the measure used by the classic chi-square
+attack to detect messages written to an image's low bits. Bits
+are counted from the least significant as with SetMsgBit.
+
+Sample values are counted in pairs that differ only in bit, such
+as 100 and 101 for bit 0. Writing a message to the bit tends to
+even out the counts of each pair, as each value of a pair is as
+likely to be written as the other, while in an untouched image
+they usually differ. The statistic sums how far each pair is from
+being even, so the lower it is the more the image looks as
+though it holds a message; a value well below the number of
+pairs present suggests the whole plane has been written to. The
+red, green and blue samples are counted together, or the single
+sample of gray and paletted images. Alpha is left out as it is
+usually the same for every pixel.
+
+Running ChiSquareLSB on a cover image and on the same image with
+a message encoded into it shows how much more detectable
+encoding has made it, for instance to compare bit depths, spread
+or a smaller region. An error is returned if src is of a type
+Encode doesn't support or bit is outside its samples.
+*/
+func ChiSquareLSB(src string, bit int) (float64, error) {
+
+	img, err := readImage(src)
+	if err != nil {
+		return 0, err
+	}
+
+	return chiSquareLSB(img, bit)
+}
+
+func chiSquareLSB(img image.Image, bit int) (float64, error) {
+
+	if err := checkImage(img); err != nil {
+		return 0, err
+	}
+
+	c, err := newCarrier(img)
+	if err != nil {
+		return 0, err
+	}
+
+	if n := c.sampleBits(); bit < 0 || bit >= n {
+		return 0, fmt.Errorf("bit out of bounds: got %d, wanted 0-%d inclusive", bit, n-1)
+	}
+
+	channels := []Channel{ChannelRed, ChannelGreen, ChannelBlue}
+	if c.check(channels) != nil {
+		channels = channels[:1]
+	}
+
+	hist := make([]int, 1<<uint(c.sampleBits()))
+	bounds := c.bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			for _, ch := range channels {
+				hist[c.sample(x, y, ch)]++
+			}
+		}
+	}
+
+	mask := 1 << uint(bit)
+	var chi float64
+
+	for v := range hist {
+		if v&mask != 0 {
+			continue
+		}
+		n0, n1 := float64(hist[v]), float64(hist[v|mask])
+		expected := (n0 + n1) / 2
+		if expected == 0 {
+			continue
+		}
+		d := n0 - expected
+		chi += d * d / expected
+	}
+
+	return chi, nil
+}
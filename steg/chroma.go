@@ -0,0 +1,187 @@
+package steg
+
+import (
+	"errors"
+	"image/color"
+)
+
+/*
+SetChroma specifies whether messages are written to the blue
+and red difference (Cb and Cr) channels of each pixel instead of
+the channels set with SetChannel or SetChannels, which are then
+ignored. Eyes are less sensitive to changes in colour than in
+brightness so this is harder to see than writing to the red,
+green and blue channels directly. Each pixel holds two bits of
+message per bit of depth, first in Cb then in Cr. The msg bit,
+bit depth and bit hopping settings apply to the Cb and Cr
+channels as they would to any other.
+
+Pixels are converted to YCbCr with image/color, which rounds, so
+a pixel can't always be given the exact Cb and Cr wanted. Each
+pixel written to is therefore set to the colour closest to the
+original whose Cb and Cr hold the message bits once converted.
+With a bit depth of 1 and a msg bit of 0 or 1 such a colour can
+always be found. Higher bits and greater depths need larger
+changes to Cb and Cr, which saturated colours often can't make
+without leaving the range of RGB, and the bits that can't be
+written are lost; use a checksum (see SetChecksum) to detect
+this if those settings are used.
+
+Chroma mode only works with RGBA and NRGBA images and their
+16-bit counterparts, and is disabled by default.
+*/
+func (e *Encoder) SetChroma(enabled bool) {
+	e.chroma = enabled
+}
+
+// chanCb and chanCr are the channels used in chroma mode. They
+// follow the exported channels so that they can't be set with
+// SetChannel.
+const (
+	chanCb = ChannelAlpha + 1 + iota
+	chanCr
+)
+
+var chromaChannels = []Channel{chanCb, chanCr}
+
+// planeMask returns a mask of the bit planes message bits may
+// be written to.
+func (e *Encoder) planeMask() (mask uint16) {
+	if e.hopKey != "" {
+		return uint16(1<<uint(e.hopMax+1) - 1)
+	}
+	depth := e.bitDepth()
+	if e.headerDepth > depth {
+		depth = e.headerDepth
+	}
+	for _, c := range e.activeChannels() {
+		for j := 0; j < depth; j++ {
+			mask |= 1 << uint(e.msgBit(c)+j)
+		}
+	}
+	return mask
+}
+
+// chromaRadius is how far from the original value each of the
+// red, green and blue channels is searched when setting Cb or
+// Cr.
+const chromaRadius = 3
+
+/*
+chromaCarrier presents the Cb and Cr channels of a carrier's
+red, green and blue channels. Only the bits in mask are kept
+stable when writing, as requiring every bit of Cb and Cr to
+survive the conversion back to RGB would rule out many more
+colours. Cb and Cr are 8-bit, so with 16-bit images they are
+worked out from the high byte of each sample and the low byte is
+left as it was.
+*/
+type chromaCarrier struct {
+	carrier
+	mask byte
+}
+
+func (c chromaCarrier) sampleBits() int {
+	return 8
+}
+
+// shift is how far samples of the underlying carrier are
+// shifted to give 8-bit values.
+func (c chromaCarrier) shift() uint {
+	return uint(c.carrier.sampleBits() - 8)
+}
+
+func (c chromaCarrier) setRGB(x, y int, ch Channel, v uint8) {
+	s := c.shift()
+	low := c.carrier.sample(x, y, ch) & (1<<s - 1)
+	c.carrier.setSample(x, y, ch, uint16(v)<<s|low)
+}
+
+func (c chromaCarrier) check(cs []Channel) error {
+	switch c.carrier.(type) {
+	case rgbaCarrier, rgba64Carrier:
+		return nil
+	}
+	return errors.New("chroma mode requires an RGBA image or one of its variants")
+}
+
+func (c chromaCarrier) rgb(x, y int) (r, g, b uint8) {
+	s := c.shift()
+	return uint8(c.carrier.sample(x, y, ChannelRed) >> s),
+		uint8(c.carrier.sample(x, y, ChannelGreen) >> s),
+		uint8(c.carrier.sample(x, y, ChannelBlue) >> s)
+}
+
+func (c chromaCarrier) sample(x, y int, ch Channel) uint16 {
+	_, cb, cr := color.RGBToYCbCr(c.rgb(x, y))
+	if ch == chanCb {
+		return uint16(cb)
+	}
+	return uint16(cr)
+}
+
+func (c chromaCarrier) setSample(x, y int, ch Channel, v16 uint16) {
+
+	v := uint8(v16)
+	if (uint8(c.sample(x, y, ch))^v)&c.mask == 0 {
+		return
+	}
+
+	r, g, b := c.rgb(x, y)
+	yy, cb, cr := color.RGBToYCbCr(r, g, b)
+
+	if ch == chanCb {
+		cb = v
+	} else {
+		cr = v
+	}
+
+	// Search around the colour converted back from the wanted
+	// Cb and Cr, choosing the candidate closest to the original.
+	r0, g0, b0 := color.YCbCrToRGB(yy, cb, cr)
+
+	best := -1
+	var br, bg, bb uint8
+
+	for dr := -chromaRadius; dr <= chromaRadius; dr++ {
+		for dg := -chromaRadius; dg <= chromaRadius; dg++ {
+			for db := -chromaRadius; db <= chromaRadius; db++ {
+
+				nr, ok1 := offsetSample(r0, dr)
+				ng, ok2 := offsetSample(g0, dg)
+				nb, ok3 := offsetSample(b0, db)
+				if !ok1 || !ok2 || !ok3 {
+					continue
+				}
+
+				_, ncb, ncr := color.RGBToYCbCr(nr, ng, nb)
+				if (ncb^cb)&c.mask != 0 || (ncr^cr)&c.mask != 0 {
+					continue
+				}
+
+				d := sqDist(nr, r) + sqDist(ng, g) + sqDist(nb, b)
+				if best < 0 || d < best {
+					best, br, bg, bb = d, nr, ng, nb
+				}
+			}
+		}
+	}
+
+	if best < 0 {
+		return
+	}
+
+	c.setRGB(x, y, ChannelRed, br)
+	c.setRGB(x, y, ChannelGreen, bg)
+	c.setRGB(x, y, ChannelBlue, bb)
+}
+
+func sqDist(a, b uint8) int {
+	d := int(a) - int(b)
+	return d * d
+}
+
+func offsetSample(v uint8, d int) (uint8, bool) {
+	n := int(v) + d
+	return uint8(n), n >= 0 && n <= 255
+}
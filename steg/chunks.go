@@ -0,0 +1,116 @@
+package steg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io"
+)
+
+/*
+SetPNGChunks specifies whether metadata chunks of a PNG src are
+copied to dst when dst is also a PNG. png.Encode otherwise drops
+them. The chunks copied are text (tEXt, zTXt and iTXt), colour
+space information (gAMA, cHRM, sRGB and iCCP), pixel dimensions
+(pHYs), modification time (tIME) and Exif data (eXIf). Other
+chunks describe the pixel data and may not be valid once it has
+been re-encoded so they are not copied. Copying is disabled by
+default.
+*/
+func (e *Encoder) SetPNGChunks(enabled bool) {
+	e.keepChunks = enabled
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// keptChunks are the chunk types copied by SetPNGChunks.
+var keptChunks = map[string]bool{
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"gAMA": true,
+	"cHRM": true,
+	"sRGB": true,
+	"iCCP": true,
+	"pHYs": true,
+	"tIME": true,
+	"eXIf": true,
+}
+
+/*
+pngChunks returns the chunks of the PNG in data that are copied
+by SetPNGChunks, each including its length, type and CRC. It
+returns nil if data isn't a PNG or is malformed.
+*/
+func pngChunks(data []byte) (chunks [][]byte) {
+	for _, chunk := range splitPNGChunks(data) {
+		if keptChunks[string(chunk[4:8])] {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks
+}
+
+// splitPNGChunks is like pngChunks but returns every chunk.
+func splitPNGChunks(data []byte) (chunks [][]byte) {
+
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil
+	}
+	data = data[len(pngSignature):]
+
+	for len(data) >= 12 {
+
+		n := int(binary.BigEndian.Uint32(data))
+		if n < 0 || n > len(data)-12 {
+			return nil
+		}
+
+		chunks = append(chunks, data[:12+n])
+		data = data[12+n:]
+	}
+
+	return chunks
+}
+
+/*
+keepChunks wraps enc so that chunks are inserted after the IHDR
+chunk of its output, which places them before the PLTE and IDAT
+chunks as some of them require. Output that isn't a PNG is
+written unchanged.
+*/
+func keepChunks(enc imageEncoder, chunks [][]byte) imageEncoder {
+
+	if len(chunks) == 0 {
+		return enc
+	}
+
+	return func(w io.Writer, img image.Image) error {
+
+		var buf bytes.Buffer
+		if err := enc(&buf, img); err != nil {
+			return err
+		}
+
+		out := buf.Bytes()
+		if !bytes.HasPrefix(out, pngSignature) || len(out) < len(pngSignature)+12 {
+			_, err := w.Write(out)
+			return err
+		}
+
+		// IHDR is always the first chunk.
+		i := len(pngSignature)
+		i += 12 + int(binary.BigEndian.Uint32(out[i:]))
+
+		if _, err := w.Write(out[:i]); err != nil {
+			return err
+		}
+		for _, c := range chunks {
+			if _, err := w.Write(c); err != nil {
+				return err
+			}
+		}
+		_, err := w.Write(out[i:])
+		return err
+	}
+}
@@ -0,0 +1,91 @@
+package steg
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	compressStored   = 0
+	compressDeflated = 1
+
+	// flag byte followed by a big-endian uint32 length
+	compressHeaderSize = 5
+)
+
+/*
+compress deflates msg and prefixes it with a flag byte and the
+length of the data that follows. If deflating does not make msg
+smaller it is stored as is and the flag records this.
+*/
+func compress(msg []byte) ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	flag := byte(compressDeflated)
+	data := buf.Bytes()
+	if len(data) >= len(msg) {
+		flag = compressStored
+		data = msg
+	}
+
+	out := make([]byte, compressHeaderSize, compressHeaderSize+len(data))
+	out[0] = flag
+	binary.BigEndian.PutUint32(out[1:], uint32(len(data)))
+
+	return append(out, data...), nil
+}
+
+// decompress reverses compress, returning an error wrapping
+// ErrDecodeLimit if the result is longer than limit bytes and
+// limit isn't zero.
+func decompress(data []byte, limit int) ([]byte, error) {
+
+	if len(data) < compressHeaderSize {
+		return nil, errors.New("decoded data is too short to hold a compression header")
+	}
+
+	flag := data[0]
+	n := int(binary.BigEndian.Uint32(data[1:]))
+	data = data[compressHeaderSize:]
+
+	if n > len(data) {
+		return nil, errors.New("compressed length exceeds decoded data")
+	}
+	data = data[:n]
+
+	switch flag {
+	case compressStored:
+		return data, nil
+	case compressDeflated:
+		r := flate.NewReader(bytes.NewReader(data))
+		if limit == 0 {
+			return io.ReadAll(r)
+		}
+		b, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > limit {
+			return nil, fmt.Errorf("%w: message decompresses to more than %d bytes", ErrDecodeLimit, limit)
+		}
+		return b, nil
+	}
+
+	return nil, errors.New("unknown compression flag")
+}
@@ -0,0 +1,37 @@
+package steg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompressionUsesFewerPixels(t *testing.T) {
+
+	msg := strings.Repeat("all work and no play makes jack a dull boy ", 40)
+
+	var plain, compressed Encoder
+	plain.SetLengthHeader(true)
+	compressed.SetLengthHeader(true)
+	compressed.SetCompression(true)
+
+	var written [2]int
+	for i, e := range []*Encoder{&plain, &compressed} {
+		img := noisyNRGBA(160, 160)
+		end, err := e.EncodeImage(img, msg, Point{})
+		if err != nil {
+			t.Fatalf("encoder %d: %v", i, err)
+		}
+		got, err := e.DecodeAutoImage(img, Point{})
+		if err != nil {
+			t.Fatalf("encoder %d: %v", i, err)
+		}
+		if got != msg {
+			t.Fatalf("encoder %d: decoded message differs from the one encoded", i)
+		}
+		written[i] = end.Y*160 + end.X
+	}
+
+	if written[1]*10 > written[0] {
+		t.Fatalf("compressed message took %d pixels, want under a tenth of the %d uncompressed", written[1], written[0])
+	}
+}
@@ -0,0 +1,104 @@
+package steg
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+/*
+EncodeNewImage is like Encode but rather than reading a cover
+image it creates a width x height *image.RGBA to write msg to,
+for when there is no cover image to hand. The image is filled
+with fill or, if fill is nil, with opaque random noise, against
+which changes to the message bits can't be seen. A fill that
+isn't opaque gives an *image.NRGBA instead, as saving
+premultiplied colours that aren't opaque loses their low bits.
+dst is written in the format given by its extension as with
+Encode.
+
+EncodeNewImage returns an error if width or height are not
+positive or the image would be too large to allocate.
+*/
+func (e *Encoder) EncodeNewImage(dst, msg string, width, height int, fill color.Color, start Point) (end Point, err error) {
+
+	if width <= 0 || height <= 0 || width > math.MaxInt/4/height {
+		return end, fmt.Errorf("image size out of bounds: got %dx%d, wanted a positive width and height", width, height)
+	}
+
+	enc, err := e.encoderFor(dst)
+	if err != nil {
+		return end, err
+	}
+
+	dst, err = filepath.Abs(dst)
+	if err != nil {
+		return end, err
+	}
+
+	if err = e.checkDstDir(dst); err != nil {
+		return end, err
+	}
+
+	img, err := newCover(width, height, fill)
+	if err != nil {
+		return end, err
+	}
+	if e.usesChannel(ChannelAlpha) {
+		img = nonPremultiplied(img)
+	}
+
+	stats, err := e.encodeImage(context.Background(), img, []byte(msg), start)
+	end = stats.End
+	if err != nil {
+		return end, err
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if err = enc(buf, img); err != nil {
+		return end, err
+	}
+
+	w, err := os.Create(dst)
+	if err != nil {
+		return end, err
+	}
+	defer w.Close()
+
+	_, err = buf.WriteTo(w)
+	return end, err
+}
+
+// newCover returns an image filled with fill, or with random
+// noise if fill is nil.
+func newCover(width, height int, fill color.Color) (draw.Image, error) {
+
+	r := image.Rect(0, 0, width, height)
+
+	if fill != nil {
+		var img draw.Image = image.NewRGBA(r)
+		if _, _, _, a := fill.RGBA(); a != 0xffff {
+			img = image.NewNRGBA(r)
+		}
+		draw.Draw(img, r, image.NewUniform(fill), image.Point{}, draw.Src)
+		return img, nil
+	}
+
+	img := image.NewRGBA(r)
+	if _, err := rand.Read(img.Pix); err != nil {
+		return nil, err
+	}
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xff
+	}
+
+	return img, nil
+}
@@ -0,0 +1,88 @@
+package steg
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	saltSize = 16
+	keySize  = 32 // AES-256
+
+	// cryptOverhead is how many bytes encrypt adds: the salt
+	// plus GCM's standard 12 byte nonce and 16 byte tag.
+	cryptOverhead = saltSize + 12 + 16
+)
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, keySize)
+}
+
+/*
+encrypt seals plain with AES-256-GCM using a key derived from
+passphrase. The result is laid out as salt, nonce, ciphertext.
+*/
+func encrypt(passphrase string, plain []byte) ([]byte, error) {
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := append(salt, nonce...)
+	return gcm.Seal(out, nonce, plain, nil), nil
+}
+
+// decrypt reverses encrypt.
+func decrypt(passphrase string, data []byte) ([]byte, error) {
+
+	if len(data) < saltSize {
+		return nil, ErrAuthentication
+	}
+	salt, data := data[:saltSize], data[saltSize:]
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrAuthentication
+	}
+	nonce, data := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+
+	plain, err := gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return nil, ErrAuthentication
+	}
+
+	return plain, nil
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,48 @@
+package steg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPassphrase(t *testing.T) {
+
+	const msg = "encrypted message"
+
+	img := noisyNRGBA(40, 40)
+	var e Encoder
+	e.SetLengthHeader(true)
+	e.SetPassphrase("correct horse")
+
+	end, err := e.EncodeImage(img, msg, Point{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var plain Encoder
+	plain.SetLengthHeader(true)
+	raw, err := plain.DecodeImage(img, Point{}, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) < len(msg)+44 {
+		t.Errorf("got %d bytes of raw payload, want at least %d", len(raw), len(msg)+44)
+	}
+
+	got, err := e.DecodeAutoImage(img, Point{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != msg {
+		t.Errorf("got %q, want %q", got, msg)
+	}
+
+	e.SetPassphrase("wrong horse")
+	got, err = e.DecodeAutoImage(img, Point{})
+	if !errors.Is(err, ErrAuthentication) {
+		t.Errorf("got error %v, want %v", err, ErrAuthentication)
+	}
+	if got != "" {
+		t.Errorf("got %q with the wrong passphrase, want nothing", got)
+	}
+}
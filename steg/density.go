@@ -0,0 +1,126 @@
+package steg
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+)
+
+/*
+SetDensity makes the encoder skip pixels in flat areas of the
+image, where changes to the message bits are easiest to detect,
+and only write to pixels in textured areas. The texture of a
+pixel is the variance of the average of its red, green and
+blue samples over the window x window square of pixels centred
+on it; pixels whose variance is not above threshold are
+skipped. The bit planes message bits are written to are left out
+of the average so that decoding, which must use the same
+threshold and window, finds the same pixels.
+
+How many pixels are textured enough depends entirely on the
+cover image, so the capacity reported by Capacity and
+CapacityInfo is an upper bound once density is enabled, and
+PointAfter and RegionFor don't take it into account. Density
+cannot be combined with SetSpread, SetChroma or a null
+terminator, and FindStart and DecodeUntilNull can't be used
+with it.
+
+SetDensity returns an error if window is not an odd number of at
+least 3 or threshold is negative. A window of zero disables
+density, which is the default.
+*/
+func (e *Encoder) SetDensity(threshold float64, window int) error {
+	if window == 0 {
+		e.densityWindow = 0
+		e.densityThreshold = 0
+		return nil
+	}
+	if window < 3 || window%2 == 0 {
+		return fmt.Errorf("density window out of bounds: got %d, wanted an odd number of at least 3", window)
+	}
+	if threshold < 0 || math.IsNaN(threshold) {
+		return fmt.Errorf("density threshold out of bounds: got %g, wanted 0 or more", threshold)
+	}
+	e.densityWindow = window
+	e.densityThreshold = threshold
+	return nil
+}
+
+func (e *Encoder) checkDensity() error {
+	if e.densityWindow == 0 {
+		return nil
+	}
+	if e.spread || e.chroma || e.terminator {
+		return errors.New("density cannot be combined with spread, chroma or a null terminator")
+	}
+	return nil
+}
+
+/*
+denseOffsets returns the offsets from start, in traversal
+order, of the pixels of c that are textured enough to hold
+message bits, or nil if density is disabled. The last pixel of
+c is never included so that the point after the message is
+always in bounds.
+*/
+func (e *Encoder) denseOffsets(c carrier, start Point) []int {
+
+	if e.densityWindow == 0 {
+		return nil
+	}
+
+	bounds := c.bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	mask := e.planeMask()
+
+	// Summed-area tables of the masked sample sums and of their
+	// squares, with a row and column of zeroes at the top left.
+	sum := getInt64s((w + 1) * (h + 1))
+	sq := getInt64s((w + 1) * (h + 1))
+	defer putInt64s(sum)
+	defer putInt64s(sq)
+	for y := 0; y < h; y++ {
+		var rowSum, rowSq int64
+		for x := 0; x < w; x++ {
+			var v int64
+			for ch := ChannelRed; ch <= ChannelBlue; ch++ {
+				v += int64(c.sample(bounds.Min.X+x, bounds.Min.Y+y, ch) &^ mask)
+			}
+			rowSum += v
+			rowSq += v * v
+			i := (y+1)*(w+1) + x + 1
+			sum[i] = sum[i-w-1] + rowSum
+			sq[i] = sq[i-w-1] + rowSq
+		}
+	}
+
+	area := func(t []int64, r image.Rectangle) int64 {
+		return t[r.Max.Y*(w+1)+r.Max.X] - t[r.Min.Y*(w+1)+r.Max.X] -
+			t[r.Max.Y*(w+1)+r.Min.X] + t[r.Min.Y*(w+1)+r.Min.X]
+	}
+
+	half := e.densityWindow / 2
+	local := image.Rect(0, 0, w, h)
+
+	first := offsetFromMin(bounds, e.traversal, start)
+	dense := make([]int, 0, w*h-first)
+
+	for i := first; i < w*h-1; i++ {
+
+		p := pointAt(local, e.traversal, i)
+		r := image.Rect(p.X-half, p.Y-half, p.X+half+1, p.Y+half+1).Intersect(local)
+
+		// Sums are of three samples, hence the division by 9 to
+		// give the variance of their average.
+		n := int64(r.Dx() * r.Dy())
+		s := area(sum, r)
+		variance := float64(n*area(sq, r)-s*s) / float64(n*n) / 9
+
+		if variance > e.densityThreshold {
+			dense = append(dense, i-first)
+		}
+	}
+
+	return dense
+}
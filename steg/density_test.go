@@ -0,0 +1,68 @@
+package steg
+
+import "testing"
+
+func TestDensity(t *testing.T) {
+
+	e, err := NewEncoder(WithDensity(10, 3), WithLengthHeader(), WithChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The top half of the image is flat and the bottom half noise.
+	orig := noisyNRGBA(32, 32)
+	for i := 0; i < len(orig.Pix)/2; i++ {
+		orig.Pix[i] = 0x80
+		if i%4 == 3 {
+			orig.Pix[i] = 0xff
+		}
+	}
+
+	const msg = "only in textured areas"
+	img := copyNRGBA(orig)
+	end, err := e.EncodeImage(img, msg, Point{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The window of a pixel in the last flat row takes in the
+	// first row of noise.
+	for _, i := range changedPixels(orig, img) {
+		if y := i / 32; y < 15 {
+			t.Fatalf("pixel %d in a flat area changed", i)
+		}
+	}
+
+	got, err := e.DecodeImage(img, Point{}, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != msg {
+		t.Fatalf("got %q, want %q", got, msg)
+	}
+}
+
+func TestDensitySettings(t *testing.T) {
+
+	var e Encoder
+	for _, tt := range []struct {
+		threshold float64
+		window    int
+	}{
+		{1, 1}, {1, 2}, {1, -3}, {-1, 3},
+	} {
+		if err := e.SetDensity(tt.threshold, tt.window); err == nil {
+			t.Errorf("SetDensity accepted a threshold of %g and a window of %d", tt.threshold, tt.window)
+		}
+	}
+
+	for name, opts := range map[string][]Option{
+		"spread":            {WithLengthHeader(), WithSpread()},
+		"chroma":            {WithChroma()},
+		"a null terminator": {WithNullTerminator()},
+	} {
+		if _, err := NewEncoder(append(opts, WithDensity(1, 3))...); err == nil {
+			t.Errorf("density was accepted with %s", name)
+		}
+	}
+}
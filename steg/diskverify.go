@@ -0,0 +1,41 @@
+package steg
+
+import (
+	"bytes"
+	"fmt"
+)
+
+/*
+SetVerifyOnDisk specifies whether Encode checks the file it has
+written. When enabled, once dst has been saved and closed it is
+read back from disk and the message decoded from between start
+and end, and ErrVerifyFailed is returned if that fails or gives
+anything other than msg. Unlike EncodeAndVerify, which decodes
+the image before it is saved, this also catches a message lost
+in encoding the output file or in writing it to disk. dst is
+left in place when verification fails so that it can be looked
+at. The check is made by the same functions that write a sidecar
+(see SetSidecar). It is disabled by default.
+*/
+func (e *Encoder) SetVerifyOnDisk(enabled bool) {
+	e.verifyDisk = enabled
+}
+
+// verifyFile checks that the image at dst holds msg between
+// start and end, if on-disk verification is enabled.
+func (e *Encoder) verifyFile(dst string, msg []byte, start, end Point) error {
+
+	if !e.verifyDisk {
+		return nil
+	}
+
+	got, err := e.DecodeBytes(dst, start, end)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrVerifyFailed, err)
+	}
+	if !bytes.Equal(got, msg) {
+		return fmt.Errorf("%w: %s", ErrVerifyFailed, dst)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package steg
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+/*
+Distortion compares the cover image at coverPath with the image
+at stegoPath that a message was encoded into and returns the mean
+squared error and peak signal-to-noise ratio between them. Both
+are measured on the 0-255 scale of 8-bit samples and over every
+channel of the images, so a gray image is compared on its single
+channel and other images on red, green, blue and alpha. A higher
+PSNR means the message is harder to see; identical images have
+an MSE of 0 and a PSNR of +Inf.
+
+An error is returned if the images have different bounds or
+colour models. Note that Encode saves premultiplied images with
+a non-premultiplied colour model when writing to the alpha
+channel, in which case the images cannot be compared.
+*/
+func Distortion(coverPath, stegoPath string) (mse, psnr float64, err error) {
+
+	cover, err := readImage(coverPath)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	stego, err := readImage(stegoPath)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return distortion(cover, stego)
+}
+
+func distortion(cover, stego image.Image) (mse, psnr float64, err error) {
+
+	b := cover.Bounds()
+	if b != stego.Bounds() {
+		return 0, 0, fmt.Errorf("image bounds differ: got %v and %v", b, stego.Bounds())
+	}
+	if cover.ColorModel() != stego.ColorModel() {
+		return 0, 0, errors.New("image colour models differ")
+	}
+	if b.Empty() {
+		return 0, 0, errors.New("images are empty")
+	}
+
+	gray := cover.ColorModel() == color.GrayModel || cover.ColorModel() == color.Gray16Model
+
+	var sum float64
+	var n int
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+
+			c1, c2 := cover.At(x, y), stego.At(x, y)
+
+			if gray {
+				g1 := color.Gray16Model.Convert(c1).(color.Gray16)
+				g2 := color.Gray16Model.Convert(c2).(color.Gray16)
+				sum += sqDiff(g1.Y, g2.Y)
+				n++
+				continue
+			}
+
+			// Compare non-premultiplied values so that changes to
+			// the colour of transparent pixels are counted.
+			n1, n2 := toNRGBA64(c1), toNRGBA64(c2)
+			sum += sqDiff(n1.R, n2.R) + sqDiff(n1.G, n2.G) + sqDiff(n1.B, n2.B) + sqDiff(n1.A, n2.A)
+			n += 4
+		}
+	}
+
+	mse = sum / float64(n)
+	if mse == 0 {
+		return 0, math.Inf(1), nil
+	}
+
+	return mse, 10 * math.Log10(255*255/mse), nil
+}
+
+// toNRGBA64 converts c without going through premultiplied
+// values when c is already non-premultiplied, as converting
+// through them loses the colour of transparent pixels.
+func toNRGBA64(c color.Color) color.NRGBA64 {
+	switch c := c.(type) {
+	case color.NRGBA:
+		return color.NRGBA64{
+			R: uint16(c.R) * 0x101,
+			G: uint16(c.G) * 0x101,
+			B: uint16(c.B) * 0x101,
+			A: uint16(c.A) * 0x101,
+		}
+	case color.NRGBA64:
+		return c
+	}
+	return color.NRGBA64Model.Convert(c).(color.NRGBA64)
+}
+
+// sqDiff returns the squared difference between two 16-bit
+// colour values on the 8-bit scale.
+func sqDiff(a, b uint16) float64 {
+	d := (float64(a) - float64(b)) / 257
+	return d * d
+}
@@ -0,0 +1,200 @@
+package steg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+)
+
+/*
+ErrorCorrection specifies how message bits are protected against
+changes to the image after encoding.
+*/
+type ErrorCorrection int
+
+const (
+	// ECCNone writes each bit of the message once.
+	ECCNone ErrorCorrection = iota
+
+	// ECCRepetition3 writes each bit three times and decodes
+	// it by majority vote, correcting one changed bit in each
+	// three.
+	ECCRepetition3
+
+	// ECCHamming74 writes each four bits as a seven bit
+	// Hamming code, correcting one changed bit in each seven.
+	ECCHamming74
+)
+
+// maxByteBits is the most bits a byte is written as.
+const maxByteBits = 24
+
+func (c ErrorCorrection) valid() bool {
+	return c >= ECCNone && c <= ECCHamming74
+}
+
+func (c ErrorCorrection) String() string {
+	switch c {
+	case ECCNone:
+		return "none"
+	case ECCRepetition3:
+		return "repetition3"
+	case ECCHamming74:
+		return "hamming74"
+	}
+	return fmt.Sprintf("ErrorCorrection(%d)", int(c))
+}
+
+/*
+SetErrorCorrection specifies a forward error correcting code
+that every byte written to the image, including the magic marker
+and length header, is encoded with, so that messages can still
+be decoded after a few of the bits holding them have been
+changed, for instance by light recompression. Decoding corrects
+errors transparently and DecodeCorrected reports how many bits
+were corrected. Messages must be decoded with the same error
+correction.
+
+ECCRepetition3 takes up three times as many bits as the message
+and ECCHamming74 seven bits for every four, or 14 bits a byte.
+Capacity, PointAfter and the other functions that work out how
+many pixels a message needs take the expansion into account.
+Changes beyond what the code can correct go undetected unless a
+checksum is also used (see SetChecksum). Error correction cannot
+be combined with a null terminator, and is not recorded by the
+format header.
+
+If ecc is not a valid ErrorCorrection SetErrorCorrection returns
+an error. By default no error correction is used.
+*/
+func (e *Encoder) SetErrorCorrection(ecc ErrorCorrection) error {
+	if !ecc.valid() {
+		return fmt.Errorf("invalid error correction: got %d", ecc)
+	}
+	e.ecc = ecc
+	return nil
+}
+
+func (e *Encoder) checkErrorCorrection() error {
+	if e.ecc != ECCNone && e.terminator {
+		return errors.New("error correction cannot be combined with a null terminator")
+	}
+	return nil
+}
+
+// byteBits returns how many bits each byte is written as.
+func (e *Encoder) byteBits() int {
+	switch e.ecc {
+	case ECCRepetition3:
+		return 24
+	case ECCHamming74:
+		return 14
+	}
+	return 8
+}
+
+// encodeByte sets the first byteBits bits of bits to the code
+// for b.
+func (e *Encoder) encodeByte(bits *[maxByteBits]bool, b byte) {
+
+	var d [8]bool
+	byteToBits(&d, b, e.bitOrder)
+
+	switch e.ecc {
+
+	case ECCRepetition3:
+		for i, bit := range d {
+			bits[3*i], bits[3*i+1], bits[3*i+2] = bit, bit, bit
+		}
+
+	case ECCHamming74:
+		// Each nibble becomes p1 p2 d1 p3 d2 d3 d4.
+		for i := 0; i < 2; i++ {
+			d1, d2, d3, d4 := d[4*i], d[4*i+1], d[4*i+2], d[4*i+3]
+			c := bits[7*i : 7*i+7]
+			c[0] = d1 != d2 != d4
+			c[1] = d1 != d3 != d4
+			c[2] = d1
+			c[3] = d2 != d3 != d4
+			c[4], c[5], c[6] = d2, d3, d4
+		}
+
+	default:
+		copy(bits[:], d[:])
+	}
+}
+
+// decodeByte is the inverse of encodeByte, also returning how
+// many bits were corrected.
+func (e *Encoder) decodeByte(bits []bool) (b byte, fixed int) {
+
+	var d [8]bool
+
+	switch e.ecc {
+
+	case ECCRepetition3:
+		for i := range d {
+			n := 0
+			for _, bit := range bits[3*i : 3*i+3] {
+				if bit {
+					n++
+				}
+			}
+			d[i] = n >= 2
+			if n == 1 || n == 2 {
+				fixed++
+			}
+		}
+
+	case ECCHamming74:
+		for i := 0; i < 2; i++ {
+			var c [7]bool
+			copy(c[:], bits[7*i:7*i+7])
+
+			// The syndrome is the position, counting from 1, of
+			// a single changed bit.
+			s := 0
+			for p := 1; p <= 7; p++ {
+				if c[p-1] {
+					s ^= p
+				}
+			}
+			if s != 0 {
+				c[s-1] = !c[s-1]
+				fixed++
+			}
+			d[4*i], d[4*i+1], d[4*i+2], d[4*i+3] = c[2], c[4], c[5], c[6]
+		}
+
+	default:
+		copy(d[:], bits)
+	}
+
+	return bitsToByte(d, e.bitOrder), fixed
+}
+
+/*
+DecodeCorrected is like Decode but also returns how many bits
+of the message, including its magic marker and headers, were
+corrected by error correction (see SetErrorCorrection). It is
+always zero when error correction is not enabled.
+*/
+func (e *Encoder) DecodeCorrected(src string, start, end Point) (msg string, corrected int, err error) {
+
+	img, err := readImage(src)
+	if err != nil {
+		return msg, 0, err
+	}
+
+	return e.DecodeImageCorrected(img, start, end)
+}
+
+// DecodeImageCorrected is like DecodeCorrected but reads msg
+// directly from img.
+func (e *Encoder) DecodeImageCorrected(img image.Image, start, end Point) (msg string, corrected int, err error) {
+	d := *e
+	d.corrected = &corrected
+	b, err := d.decodeImage(context.Background(), img, start, end)
+	return string(b), corrected, err
+}
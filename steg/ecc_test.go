@@ -0,0 +1,81 @@
+package steg
+
+import (
+	"testing"
+)
+
+// A clean image has nothing to correct, however much of the
+// cover image the decoder has to look at to find the header.
+func TestDecodeCorrectedClean(t *testing.T) {
+
+	for _, opts := range [][]Option{
+		{WithErrorCorrection(ECCRepetition3), WithScatterSeed(3), WithLengthHeader()},
+		{WithErrorCorrection(ECCHamming74), WithScatterSeed(3), WithLengthHeader(), WithChecksum()},
+		{WithErrorCorrection(ECCHamming74), WithHeaderDepth(2), WithLengthHeader()},
+		{WithErrorCorrection(ECCRepetition3), WithHeaderDepth(3), WithLengthHeader(), WithMagic([]byte("MAGIC"))},
+		{WithErrorCorrection(ECCHamming74), WithLengthHeader()},
+		{WithErrorCorrection(ECCHamming74), WithMirror(), WithLengthHeader(), WithChecksum(), WithScatterSeed(5)},
+	} {
+
+		e, err := NewEncoder(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// A short message leaves most of the pixels read along with
+		// the header unwritten.
+		for _, msg := range []string{"hi", "nothing to correct here"} {
+
+			img := noisyNRGBA(64, 64)
+			end, err := e.EncodeImage(img, msg, Point{2, 1})
+			if err != nil {
+				t.Fatalf("%s: %v", e, err)
+			}
+
+			got, corrected, err := e.DecodeImageCorrected(img, Point{2, 1}, end)
+			if err != nil {
+				t.Fatalf("%s: %v", e, err)
+			}
+			if got != msg {
+				t.Fatalf("%s: got %q, want %q", e, got, msg)
+			}
+			if corrected != 0 {
+				t.Fatalf("%s: %q: %d bits corrected in a clean image", e, msg, corrected)
+			}
+		}
+	}
+}
+
+func TestDecodeCorrectedFlippedBit(t *testing.T) {
+
+	const msg = "one bit gets flipped"
+
+	for _, ecc := range []ErrorCorrection{ECCRepetition3, ECCHamming74} {
+
+		e, err := NewEncoder(WithErrorCorrection(ecc), WithScatterSeed(3), WithLengthHeader())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		img := noisyNRGBA(64, 64)
+		start := Point{2, 1}
+		end, err := e.EncodeImage(img, msg, start)
+		if err != nil {
+			t.Fatalf("%s: %v", e, err)
+		}
+
+		// The first pixel always holds the first bit of the header.
+		img.Pix[img.PixOffset(start.X, start.Y)] ^= 1
+
+		got, corrected, err := e.DecodeImageCorrected(img, start, end)
+		if err != nil {
+			t.Fatalf("%s: %v", e, err)
+		}
+		if got != msg {
+			t.Fatalf("%s: got %q, want %q", e, got, msg)
+		}
+		if corrected != 1 {
+			t.Fatalf("%s: %d bits corrected, want 1", e, corrected)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package steg
+
+import "errors"
+
+/*
+Errors returned by the package. They may be wrapped with more
+detail so they should be checked for with errors.Is.
+*/
+var (
+	ErrMsgEmpty         = errors.New("msg is zero length")
+	ErrStartOutOfBounds = errors.New("start point out of bounds")
+	ErrEndOutOfBounds   = errors.New("end point out of bounds")
+	ErrStartAfterEnd    = errors.New("start point does not precede end point")
+	ErrUnsupportedImage = errors.New("unsupported image type")
+	ErrVerifyFailed     = errors.New("decoded message does not match msg")
+	ErrOverlap          = errors.New("messages overlap")
+	ErrEmptyImage       = errors.New("image has no pixels")
+
+	// ErrMsgTooLarge is returned when a message needs more
+	// pixels than are available from the start point. It is
+	// wrapped along with ErrEndOutOfBounds, which was returned
+	// alone before it was added.
+	ErrMsgTooLarge = errors.New("msg is too large for the image")
+
+	// ErrNoMessage is returned when decoding an image that does
+	// not start with the encoder's magic marker, meaning no
+	// message was written there with the same settings.
+	ErrNoMessage = errors.New("no message found")
+
+	// ErrNoTerminator is returned when decoding with a null
+	// terminator (see SetNullTerminator) and no 0x00 byte is
+	// found before the end of the data read.
+	ErrNoTerminator = errors.New("message terminator not found")
+
+	// ErrChecksumMismatch is returned when the checksum stored
+	// with a message does not match the decoded data, meaning
+	// the image was altered after the message was written.
+	ErrChecksumMismatch = errors.New("message checksum mismatch")
+
+	// ErrAuthentication is returned when an encrypted message
+	// fails to decrypt, usually because the wrong passphrase
+	// was used.
+	ErrAuthentication = errors.New("message authentication failed")
+
+	// ErrDecodeLimit is returned when decoding would read more
+	// than the limit set with SetMaxDecodeBytes.
+	ErrDecodeLimit = errors.New("decode limit exceeded")
+)
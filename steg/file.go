@@ -0,0 +1,131 @@
+package steg
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/draw"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+/*
+EncodeFile is like Encode but the message is the contents of the
+file at payloadPath. The file's name and length are stored along
+with its contents so that ExtractFile can recreate it. As with
+Encode, dst is verified once saved if SetVerifyOnDisk is enabled
+and a sidecar is written if SetSidecar is, whose length is that
+of the stored name, length and contents together.
+*/
+func (e *Encoder) EncodeFile(coverSrc, dst, payloadPath string, start Point) (end Point, err error) {
+
+	data, err := os.ReadFile(payloadPath)
+	if err != nil {
+		return end, err
+	}
+
+	msg, err := packFile(filepath.Base(payloadPath), data)
+	if err != nil {
+		return end, err
+	}
+
+	return e.encodeMsgFile(coverSrc, dst, start, msg, func(img draw.Image) (Point, error) {
+		stats, err := e.encodeImage(context.Background(), img, msg, start)
+		return stats.End, err
+	})
+}
+
+/*
+ExtractFile reads a file written to stegoSrc by EncodeFile and
+saves it in outDir under its original name, returning the path
+it was saved to. Only the base of the stored name is used so the
+file is always created directly inside outDir. ExtractFile will
+not overwrite an existing file.
+*/
+func (e *Encoder) ExtractFile(stegoSrc, outDir string, start, end Point) (savedPath string, err error) {
+
+	msg, err := e.DecodeBytes(stegoSrc, start, end)
+	if err != nil {
+		return savedPath, err
+	}
+
+	name, data, err := unpackFile(msg)
+	if err != nil {
+		return savedPath, err
+	}
+
+	savedPath = filepath.Join(outDir, name)
+
+	f, err := os.OpenFile(savedPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return "", err
+	}
+
+	if err = f.Close(); err != nil {
+		return "", err
+	}
+
+	return savedPath, nil
+}
+
+/*
+packFile lays out a file as the 2 byte big-endian length of its
+name, the name, the 4 byte big-endian length of its contents and
+then the contents.
+*/
+func packFile(name string, data []byte) ([]byte, error) {
+
+	if len(name) > math.MaxUint16 {
+		return nil, errors.New("payload file name is too long")
+	}
+	if uint64(len(data)) > math.MaxUint32 {
+		return nil, errors.New("payload file is too large")
+	}
+
+	msg := make([]byte, 0, 2+len(name)+4+len(data))
+	msg = binary.BigEndian.AppendUint16(msg, uint16(len(name)))
+	msg = append(msg, name...)
+	msg = binary.BigEndian.AppendUint32(msg, uint32(len(data)))
+	msg = append(msg, data...)
+
+	return msg, nil
+}
+
+// unpackFile reverses packFile.
+func unpackFile(msg []byte) (name string, data []byte, err error) {
+
+	errCorrupt := errors.New("message does not hold a file written by EncodeFile")
+
+	if len(msg) < 2 {
+		return "", nil, errCorrupt
+	}
+	n := int(binary.BigEndian.Uint16(msg))
+	msg = msg[2:]
+
+	if len(msg) < n+4 {
+		return "", nil, errCorrupt
+	}
+	name = filepath.Base(string(msg[:n]))
+	msg = msg[n:]
+
+	n = int(binary.BigEndian.Uint32(msg))
+	msg = msg[4:]
+
+	if len(msg) < n {
+		return "", nil, errCorrupt
+	}
+
+	switch name {
+	case "", ".", "..", string(filepath.Separator):
+		return "", nil, fmt.Errorf("invalid payload file name %q", name)
+	}
+
+	return name, msg[:n], nil
+}
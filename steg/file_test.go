@@ -0,0 +1,82 @@
+package steg
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncodeFile(t *testing.T) {
+
+	data := make([]byte, 700)
+	rand.New(rand.NewSource(4)).Read(data)
+
+	payload := filepath.Join(t.TempDir(), "secret.bin")
+	if err := os.WriteFile(payload, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := NewEncoder(WithChannels(ChannelRed, ChannelGreen, ChannelBlue), WithSidecar(), WithVerifyOnDisk())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := dstPath(t, ".png")
+	start := Point{3, 2}
+	end, err := e.EncodeFile(writePNG(t, noisyNRGBA(64, 64)), dst, payload, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outDir := t.TempDir()
+	saved, err := e.ExtractFile(dst, outDir, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(outDir, "secret.bin"); saved != want {
+		t.Fatalf("saved to %s, want %s", saved, want)
+	}
+	got, err := os.ReadFile(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("extracted file differs from the payload")
+	}
+
+	if _, err = e.ExtractFile(dst, outDir, start, end); err == nil {
+		t.Fatal("ExtractFile overwrote an existing file")
+	}
+
+	// The sidecar is written as it would be by Encode.
+	msg, err := e.DecodeSidecar(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, got, err := unpackFile([]byte(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "secret.bin" || !bytes.Equal(got, data) {
+		t.Fatalf("sidecar decodes to file %q of %d bytes", name, len(got))
+	}
+}
+
+func TestExtractFileNotAFile(t *testing.T) {
+
+	e, err := NewEncoder()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := dstPath(t, ".png")
+	end, err := e.Encode(writePNG(t, noisyNRGBA(16, 16)), dst, "x", Point{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.ExtractFile(dst, t.TempDir(), Point{}, end); err == nil {
+		t.Fatal("ExtractFile succeeded on a message that isn't a file")
+	}
+}
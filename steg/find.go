@@ -0,0 +1,144 @@
+package steg
+
+import (
+	"context"
+	"errors"
+	"image"
+)
+
+/*
+FindStart searches src for the encoder's magic marker (see
+SetMagic) and returns the first point, in traversal order, at
+which it was written. The point is the start that was passed to
+Encode so it can be given to Decode or DecodeAuto to read the
+message. ErrNoMessage is returned if the marker is not found.
+
+Any pixel may be where a message starts so markers should be
+long enough to be unlikely to occur by chance; four bytes or
+more is recommended.
+*/
+func (e *Encoder) FindStart(src string) (Point, error) {
+
+	img, err := readImage(src)
+	if err != nil {
+		return Point{}, err
+	}
+
+	return e.FindStartImage(img)
+}
+
+// FindStartImage is like FindStart but takes an already
+// decoded image.
+func (e *Encoder) FindStartImage(img image.Image) (Point, error) {
+
+	if len(e.magic) == 0 {
+		return Point{}, errors.New("FindStart requires a magic marker; see SetMagic")
+	}
+	if e.densityWindow != 0 || e.formatHeader || e.stride != 0 || e.skipTransparent {
+		return Point{}, errors.New("FindStart cannot be used with density, the format header, a pixel stride or skipped transparent pixels")
+	}
+
+	c, err := e.carrierFor(img)
+	if err != nil {
+		return Point{}, err
+	}
+
+	// The marker is written at the header depth when one is set.
+	h := e
+	if e.headerDepth != 0 {
+		h = e.header()
+	}
+
+	bounds := c.bounds()
+	total := bounds.Dx() * bounds.Dy()
+	need := h.pixelsFor(len(e.magic) * e.byteBits())
+
+	for first := 0; first+need <= total; first++ {
+		if h.magicAt(c, bounds, first) {
+			return pointAt(bounds, e.traversal, first), nil
+		}
+	}
+
+	return Point{}, ErrNoMessage
+}
+
+// magicAt reports whether the magic marker was written starting
+// at the pixel with the given offset.
+func (e *Encoder) magicAt(c carrier, bounds image.Rectangle, first int) bool {
+
+	var want [maxByteBits]bool
+	channels := e.activeChannels()
+	depth := e.bitDepth()
+	bpp := e.bitsPerPixel()
+	cb := e.byteBits()
+	hops, err := e.hops(0, len(e.magic))
+	if err != nil {
+		return false
+	}
+
+	for n := 0; n < len(e.magic)*cb; n++ {
+
+		if n%cb == 0 {
+			e.encodeByte(&want, e.magic[n/cb])
+		}
+
+		p := pointAt(bounds, e.traversal, first+n/bpp)
+		ch := channels[n%bpp/depth]
+		plane := e.msgBit(ch) + n%bpp%depth
+		if hops != nil {
+			plane = int(hops[n/cb])
+		}
+
+		got := c.sample(p.X, p.Y, ch)&(1<<uint(plane)) != 0
+
+		if got != want[n%cb] {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+HasPayload reports whether a message appears to have been
+written to src at start by an encoder with the same settings.
+Only the magic marker and length header are read: the marker
+must match and the length held by the header must fit in the
+image. The message itself isn't decoded so HasPayload can't
+rule out a chance match, particularly when only the length
+header is enabled. An error is returned if neither is enabled.
+*/
+func (e *Encoder) HasPayload(src string, start Point) (bool, error) {
+
+	img, err := readImage(src)
+	if err != nil {
+		return false, err
+	}
+
+	return e.HasPayloadImage(img, start)
+}
+
+// HasPayloadImage is like HasPayload but takes an already
+// decoded image.
+func (e *Encoder) HasPayloadImage(img image.Image, start Point) (bool, error) {
+
+	if len(e.magic) == 0 && !e.lengthHeader {
+		return false, errors.New("HasPayload requires a magic marker or length header")
+	}
+
+	c, err := e.carrierFor(img)
+	if err != nil {
+		return false, err
+	}
+
+	// readHeader already checks the length fits in the image.
+	_, _, err = e.readHeader(context.Background(), c, start)
+	if errors.Is(err, ErrNoMessage) || errors.Is(err, ErrEndOutOfBounds) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
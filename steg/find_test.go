@@ -0,0 +1,73 @@
+package steg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFindStart(t *testing.T) {
+
+	magic := []byte("FIND")
+
+	for _, opts := range [][]Option{
+		{WithMagic(magic), WithLengthHeader()},
+		{WithMagic(magic), WithLengthHeader(), WithChannels(ChannelRed, ChannelBlue), WithBitDepth(2)},
+		{WithMagic(magic), WithLengthHeader(), WithHeaderDepth(3)},
+		{WithMagic(magic), WithLengthHeader(), WithTraversal(TraversalColumnMajor)},
+		{WithMagic(magic), WithLengthHeader(), WithBitHopping("key", 1)},
+	} {
+
+		e, err := NewEncoder(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, start := range []Point{{0, 0}, {13, 7}, {20, 17}} {
+
+			const msg = "found without knowing where"
+			src := writePNG(t, noisyNRGBA(32, 32))
+			dst := dstPath(t, ".png")
+			if _, err = e.Encode(src, dst, msg, start); err != nil {
+				t.Fatalf("%s from %v: %v", e, start, err)
+			}
+
+			got, err := e.FindStart(dst)
+			if err != nil {
+				t.Fatalf("%s from %v: %v", e, start, err)
+			}
+			if got != start {
+				t.Fatalf("%s: found %v, want %v", e, got, start)
+			}
+
+			if m, err := e.DecodeAuto(dst, got); err != nil || m != msg {
+				t.Fatalf("%s from %v: DecodeAuto gave %q, %v", e, start, m, err)
+			}
+		}
+
+		if _, err = e.FindStart(writePNG(t, noisyNRGBA(32, 32))); !errors.Is(err, ErrNoMessage) {
+			t.Fatalf("%s: got %v from an image without a message, want ErrNoMessage", e, err)
+		}
+	}
+}
+
+func TestFindStartSettings(t *testing.T) {
+
+	src := writePNG(t, noisyNRGBA(8, 8))
+	magic := WithMagic([]byte("FIND"))
+
+	for name, opts := range map[string][]Option{
+		"without a magic marker": {WithLengthHeader()},
+		"with density":           {magic, WithDensity(1, 3)},
+		"with the format header": {magic, WithFormatHeader(true)},
+		"with a pixel stride":    {magic, WithPixelStride(2, 1)},
+		"skipping transparent":   {magic, WithSkipTransparent()},
+	} {
+		e, err := NewEncoder(opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err = e.FindStart(src); err == nil || errors.Is(err, ErrNoMessage) {
+			t.Errorf("FindStart %s gave %v, want a settings error", name, err)
+		}
+	}
+}
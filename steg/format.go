@@ -0,0 +1,89 @@
+package steg
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg" // Register the JPEG format with image.Decode.
+	"image/png"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+// imageEncoder writes img to w in a particular image format.
+type imageEncoder func(w io.Writer, img image.Image) error
+
+/*
+encoderFor picks the format an encoded image is saved in from
+the extension of dst, defaulting to PNG. BMP decoders don't
+reliably read back alpha values so the alpha channel can't be
+used with BMP output, and BMP files only hold 8-bit samples so
+16-bit images can't be saved as BMP without losing their low
+bits.
+*/
+func (e *Encoder) encoderFor(dst string) (imageEncoder, error) {
+	switch strings.ToLower(filepath.Ext(dst)) {
+	case ".bmp":
+		if e.usesChannel(ChannelAlpha) {
+			return nil, errors.New("cannot write to the alpha channel of a BMP image")
+		}
+		return func(w io.Writer, img image.Image) error {
+			switch img.(type) {
+			case *image.RGBA64, *image.NRGBA64, *image.Gray16:
+				return fmt.Errorf("cannot save 16-bit %T as a BMP image", img)
+			}
+			return bmp.Encode(w, img)
+		}, nil
+	case ".tif", ".tiff":
+		return func(w io.Writer, img image.Image) error {
+			return tiff.Encode(w, img, &tiff.Options{Compression: tiff.Deflate})
+		}, nil
+	}
+	return e.encodePNG, nil
+}
+
+/*
+SetPNGCompression specifies the compression level used when
+saving PNG images, which only affects the size of the file and
+the time taken to write it. level must be one of the levels
+defined by image/png; the default is png.DefaultCompression.
+*/
+func (e *Encoder) SetPNGCompression(level png.CompressionLevel) error {
+	switch level {
+	case png.DefaultCompression, png.NoCompression, png.BestSpeed, png.BestCompression:
+	default:
+		return fmt.Errorf("invalid PNG compression level: got %d", level)
+	}
+	e.pngLevel = level
+	return nil
+}
+
+func (e *Encoder) encodePNG(w io.Writer, img image.Image) error {
+	enc := png.Encoder{CompressionLevel: e.pngLevel, BufferPool: pngPool}
+	return enc.Encode(w, img)
+}
+
+/*
+fromJPEG converts the image types returned by jpeg.Decode that
+have no carrier to *image.RGBA so that a message can be written
+to them. JPEG has no alpha channel so the result is opaque.
+Other images are returned as is.
+*/
+func fromJPEG(img image.Image) image.Image {
+
+	switch img.(type) {
+	case *image.YCbCr, *image.CMYK:
+	default:
+		return img
+	}
+
+	b := img.Bounds()
+	dst := image.NewRGBA(b)
+	draw.Draw(dst, b, img, b.Min, draw.Src)
+	return dst
+}
@@ -0,0 +1,111 @@
+package steg
+
+import (
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+// writeImage saves img in a temporary directory with the given
+// extension, using encode to write it, and returns its path.
+func writeImage(t testing.TB, img image.Image, ext string, encode func(io.Writer, image.Image) error) string {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), "src"+ext)
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err = encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	return src
+}
+
+// imageFormat returns the format of the image at path as named
+// by image.DecodeConfig.
+func imageFormat(t testing.TB, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	_, format, err := image.DecodeConfig(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return format
+}
+
+func TestEncodeFormats(t *testing.T) {
+
+	const msg = "lossless formats round-trip"
+
+	var e Encoder
+	e.SetLengthHeader(true)
+	if err := e.SetChannels([]Channel{ChannelRed, ChannelBlue}); err != nil {
+		t.Fatal(err)
+	}
+
+	img := noisyNRGBA(32, 32)
+	srcs := []string{
+		writePNG(t, img),
+		writeImage(t, img, ".bmp", bmp.Encode),
+		writeImage(t, img, ".tiff", func(w io.Writer, img image.Image) error {
+			return tiff.Encode(w, img, nil)
+		}),
+	}
+
+	for _, src := range srcs {
+		for ext, format := range map[string]string{
+			".png":  "png",
+			".bmp":  "bmp",
+			".tif":  "tiff",
+			".tiff": "tiff",
+			".PNG":  "png",
+		} {
+
+			dst := dstPath(t, ext)
+			if _, err := e.Encode(src, dst, msg, Point{2, 3}); err != nil {
+				t.Fatalf("%s to %s: %v", filepath.Ext(src), ext, err)
+			}
+			if got := imageFormat(t, dst); got != format {
+				t.Fatalf("%s to %s: saved as %s, want %s", filepath.Ext(src), ext, got, format)
+			}
+
+			got, err := e.DecodeAuto(dst, Point{2, 3})
+			if err != nil {
+				t.Fatalf("%s to %s: %v", filepath.Ext(src), ext, err)
+			}
+			if got != msg {
+				t.Fatalf("%s to %s: got %q, want %q", filepath.Ext(src), ext, got, msg)
+			}
+		}
+	}
+}
+
+func TestEncodeBMPErrors(t *testing.T) {
+
+	var alpha Encoder
+	alpha.SetLengthHeader(true)
+	if err := alpha.SetChannel(ChannelAlpha); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alpha.Encode(writePNG(t, noisyNRGBA(16, 16)), dstPath(t, ".bmp"), "hello", Point{}); err == nil {
+		t.Fatal("encoding to the alpha channel of a BMP succeeded")
+	}
+
+	var e Encoder
+	e.SetLengthHeader(true)
+	for _, img := range noisyDeep(16, 16) {
+		if _, err := e.Encode(writePNG(t, img), dstPath(t, ".bmp"), "hello", Point{}); err == nil {
+			t.Fatalf("saving 16-bit %T as a BMP succeeded", img)
+		}
+	}
+}
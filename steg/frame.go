@@ -0,0 +1,204 @@
+package steg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+const (
+	// maxLengthHeaderSize is the most bytes the length header,
+	// an unsigned varint, can take up.
+	maxLengthHeaderSize = binary.MaxVarintLen64
+
+	checksumSize = 4
+)
+
+// headerSize returns how many bytes frame writes before a body
+// of n bytes.
+func (e *Encoder) headerSize(n int) int {
+	size := len(e.magic)
+	if e.lengthHeader {
+		size += uvarintSize(uint64(n))
+	}
+	return size
+}
+
+// uvarintSize returns how many bytes binary.PutUvarint uses
+// to store x.
+func uvarintSize(x uint64) int {
+	n := 1
+	for x >= 0x80 {
+		x >>= 7
+		n++
+	}
+	return n
+}
+
+/*
+framedSize returns the most bytes frame can produce for a
+message of n bytes and how many of them are header. It is exact
+unless compression is enabled, in which case frame produces at
+most this many bytes.
+*/
+func (e *Encoder) framedSize(n int) (size, hdr int) {
+	n += e.metadataSize()
+	if e.compression {
+		n += compressHeaderSize
+	}
+	if e.passphrase != "" {
+		n += cryptOverhead
+	}
+	if e.checksum {
+		n += checksumSize
+	}
+	n = e.armoredSize(n)
+	if e.terminator {
+		n++
+	}
+	hdr = e.headerSize(n)
+	return n + hdr, hdr
+}
+
+// frame prepares msg for writing to an image according to
+// the encoder's settings, returning the payload and how many
+// bytes at its start are header.
+func (e *Encoder) frame(msg []byte) (payload []byte, hdr int, err error) {
+
+	msg = e.addMetadata(msg)
+
+	if e.compression {
+		b, err := compress(msg)
+		if err != nil {
+			return nil, 0, err
+		}
+		msg = b
+	}
+
+	if e.passphrase != "" {
+		b, err := encrypt(e.passphrase, msg)
+		if err != nil {
+			return nil, 0, err
+		}
+		msg = b
+	}
+
+	if e.checksum {
+		var sum [checksumSize]byte
+		binary.BigEndian.PutUint32(sum[:], crc32.ChecksumIEEE(msg))
+		msg = append(msg[:len(msg):len(msg)], sum[:]...)
+	}
+
+	msg = e.armorMsg(msg)
+
+	// The terminator can't be combined with the steps above
+	// other than armor, which never produces a NUL byte.
+	if e.terminator {
+		if bytes.IndexByte(msg, 0) >= 0 {
+			return nil, 0, errors.New("msg contains a NUL byte, which is not allowed with a null terminator")
+		}
+		msg = append(msg[:len(msg):len(msg)], 0)
+	}
+
+	payload = append([]byte(nil), e.magic...)
+	if e.lengthHeader {
+		payload = binary.AppendUvarint(payload, uint64(len(msg)))
+	}
+	hdr = len(payload)
+
+	return append(payload, msg...), hdr, nil
+}
+
+// unframe reverses frame on data read from an image.
+func (e *Encoder) unframe(data []byte) ([]byte, error) {
+	msg, err := e.unframePayload(data)
+	if err != nil {
+		return nil, err
+	}
+	return e.stripMetadata(msg)
+}
+
+// unframePayload is unframe without removing the metadata header.
+func (e *Encoder) unframePayload(data []byte) ([]byte, error) {
+
+	if err := e.checkMagic(data); err != nil {
+		return nil, err
+	}
+	data = data[len(e.magic):]
+
+	if e.terminator {
+		i := bytes.IndexByte(data, 0)
+		if i < 0 {
+			return nil, ErrNoTerminator
+		}
+		return e.unarmorMsg(data[:i])
+	}
+
+	if e.lengthHeader {
+
+		n, k := binary.Uvarint(data)
+		if k == 0 {
+			return nil, errors.New("decoded data is too short to hold a length header")
+		}
+		if k < 0 {
+			return nil, errors.New("invalid length header")
+		}
+		data = data[k:]
+
+		if n > uint64(len(data)) {
+			return nil, errors.New("length header exceeds decoded data")
+		}
+		data = data[:n]
+	}
+
+	data, err := e.unarmorMsg(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.checksum {
+
+		if len(data) < checksumSize {
+			return nil, errors.New("decoded data is too short to hold a checksum")
+		}
+
+		i := len(data) - checksumSize
+		sum := binary.BigEndian.Uint32(data[i:])
+		data = data[:i]
+
+		if crc32.ChecksumIEEE(data) != sum {
+			return nil, ErrChecksumMismatch
+		}
+		if e.verified != nil {
+			*e.verified = true
+		}
+	}
+
+	if e.passphrase != "" {
+		b, err := decrypt(e.passphrase, data)
+		if err != nil {
+			return nil, err
+		}
+		data = b
+	}
+
+	if e.compression {
+		b, err := decompress(data, e.maxDecode)
+		if err != nil {
+			return nil, err
+		}
+		data = b
+	}
+
+	return data, nil
+}
+
+// checkMagic returns ErrNoMessage if data does not begin with
+// the encoder's magic marker.
+func (e *Encoder) checkMagic(data []byte) error {
+	if !bytes.HasPrefix(data, e.magic) {
+		return ErrNoMessage
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package steg
+
+import (
+	"strings"
+	"testing"
+)
+
+// The length header is one byte for bodies under 128 bytes, two
+// under 16 KiB and so on, where the body is msg along with any
+// checksum.
+func TestLengthHeaderVarintBoundary(t *testing.T) {
+
+	for i, tt := range []struct {
+		opts     []Option
+		overhead int
+	}{
+		{[]Option{WithLengthHeader()}, 0},
+		{[]Option{WithLengthHeader(), WithChecksum(), WithMagic([]byte("MAGIC"))}, checksumSize},
+	} {
+
+		e, err := NewEncoder(tt.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, body := range []int{5, 127, 128, 129, 16383, 16384} {
+
+			header := 1
+			switch {
+			case body >= 16384:
+				header = 3
+			case body >= 128:
+				header = 2
+			}
+
+			img := noisyNRGBA(400, 400)
+			msg := strings.Repeat("v", body-tt.overhead)
+			stats, err := e.EncodeImageWithStats(img, msg, Point{7, 0})
+			if err != nil {
+				t.Fatalf("encoder %d: body %d: %v", i, body, err)
+			}
+
+			bits := offsetFromMin(img.Rect, e.traversal, stats.End) - 7
+			if want := (len(e.magic) + header + body) * 8; bits != want {
+				t.Fatalf("encoder %d: body %d: wrote %d bits, want %d for a %d byte header", i, body, bits, want, header)
+			}
+
+			got, err := e.DecodeImage(img, Point{7, 0}, stats.End)
+			if err != nil {
+				t.Fatalf("encoder %d: body %d: Decode: %v", i, body, err)
+			}
+			if got != msg {
+				t.Fatalf("encoder %d: body %d: Decode got %d bytes, want %d", i, body, len(got), len(msg))
+			}
+
+			got, err = e.DecodeAutoImage(img, Point{7, 0})
+			if err != nil {
+				t.Fatalf("encoder %d: body %d: DecodeAuto: %v", i, body, err)
+			}
+			if got != msg {
+				t.Fatalf("encoder %d: body %d: DecodeAuto got %d bytes, want %d", i, body, len(got), len(msg))
+			}
+		}
+	}
+}
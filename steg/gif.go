@@ -0,0 +1,229 @@
+package steg
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/gif"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+/*
+EncodeGIF is like EncodeSpanned but splits msg across the frames
+of the animated GIF at src, which is saved to dst as a GIF with
+the same frames, delays and disposal methods. Frames are filled
+in order, each with as much of msg as it can hold, and only
+frames that are needed are written to. Within each frame the
+message is written from start if the frame's bounds contain it
+and from the frame's top left corner otherwise, so frames that
+cover only part of the animation are still used.
+
+As for any paletted image the message is written to the palette
+index of each pixel, so the encoder must be using a single
+channel other than ChannelAlpha and each changed pixel takes the
+colour of a neighbouring palette entry. GIFs whose similar
+colours sit next to each other in the palette hide the message
+best. A frame's palette is extended, as described for
+EncodeImage, if an index past its end is written, which can't
+take it beyond the 256 colours a GIF allows.
+
+Each part is written with the same header as EncodeSpanned uses
+so that DecodeGIF can check it has every part, in order. An error
+is returned and nothing is saved if the frames can't hold msg
+between them. SetSidecar and SetVerifyOnDisk have no effect on
+EncodeGIF.
+*/
+func (e *Encoder) EncodeGIF(src, dst, msg string, start Point) error {
+
+	if len(msg) == 0 {
+		return ErrMsgEmpty
+	}
+
+	dst, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+	if err = e.checkDstDir(dst); err != nil {
+		return err
+	}
+
+	g, err := readGIF(src)
+	if err != nil {
+		return err
+	}
+
+	s := e.spanner()
+
+	var sizes []int
+	for left := len(msg); left > 0; {
+
+		if len(sizes) == len(g.Image) || len(sizes) == math.MaxUint16 {
+			return fmt.Errorf("frames can't hold msg: %d bytes are left over", left)
+		}
+
+		n, err := s.frameRoom(g.Image[len(sizes)], start)
+		if err != nil {
+			return fmt.Errorf("frame %d: %w", len(sizes), err)
+		}
+		if n > left {
+			n = left
+		}
+
+		sizes = append(sizes, n)
+		left -= n
+	}
+
+	total := countNonZero(sizes)
+	written := 0
+	part := []byte(msg)
+
+	for i, n := range sizes {
+
+		if n == 0 {
+			continue
+		}
+
+		// Frames without a local palette share the global one, so
+		// limit each palette's capacity to make extending it copy
+		// rather than write over entries other frames can see.
+		f := g.Image[i]
+		f.Palette = f.Palette[:len(f.Palette):len(f.Palette)]
+
+		payload := spanPart(written, total, part[:n])
+		part = part[n:]
+		written++
+
+		if _, err := s.encodeImage(context.Background(), f, payload, frameStart(f, start)); err != nil {
+			return fmt.Errorf("frame %d: %w", i, err)
+		}
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if err = gif.EncodeAll(buf, g); err != nil {
+		return err
+	}
+
+	w, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	_, err = buf.WriteTo(w)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return e.keepModTime(src, dst)
+}
+
+/*
+DecodeGIF returns the message written to the animated GIF at src
+by EncodeGIF, where start is the point that was passed to
+EncodeGIF. An error is returned if a part is missing or the
+frames have been reordered.
+*/
+func (e *Encoder) DecodeGIF(src string, start Point) (msg string, err error) {
+
+	g, err := readGIF(src)
+	if err != nil {
+		return msg, err
+	}
+
+	s := e.spanner()
+
+	var b []byte
+	parts, total := 0, -1
+
+	for i, f := range g.Image {
+
+		if parts == total {
+			break
+		}
+
+		n, err := s.frameRoom(f, start)
+		if err != nil {
+			return msg, fmt.Errorf("frame %d: %w", i, err)
+		}
+		if n == 0 {
+			continue
+		}
+
+		data, err := s.decodeAutoImage(f, frameStart(f, start))
+		if err != nil {
+			return msg, fmt.Errorf("frame %d: %w", i, err)
+		}
+
+		j, t, data, err := readSpanPart(data)
+		if err != nil {
+			return msg, fmt.Errorf("frame %d: %w", i, err)
+		}
+		if total == -1 {
+			total = t
+		}
+
+		switch {
+		case t != total:
+			return msg, fmt.Errorf("frame %d: part of a message with %d parts, wanted %d", i, t, total)
+		case j != parts:
+			return msg, fmt.Errorf("frame %d: holds part %d, wanted part %d", i, j, parts)
+		}
+
+		b = append(b, data...)
+		parts++
+	}
+
+	switch {
+	case total == -1:
+		return msg, ErrNoMessage
+	case parts < total:
+		return msg, fmt.Errorf("GIF holds %d of %d parts", parts, total)
+	}
+
+	return string(b), nil
+}
+
+// frameRoom returns how many bytes of a message the frame f can
+// hold after the span header.
+func (e *Encoder) frameRoom(f *image.Paletted, start Point) (int, error) {
+	ci, err := e.capacityInfo(f.Rect, frameStart(f, start))
+	if err != nil {
+		return 0, err
+	}
+	n := ci.MsgBytes() - spanHeaderSize
+	if n < 0 {
+		return 0, nil
+	}
+	return n, nil
+}
+
+// frameStart returns start if it lies in the frame f and the top
+// left corner of f otherwise.
+func frameStart(f *image.Paletted, start Point) Point {
+	if inBounds(f.Rect, start) {
+		return start
+	}
+	return Point{f.Rect.Min.X, f.Rect.Min.Y}
+}
+
+func readGIF(src string) (*gif.GIF, error) {
+
+	src, err := filepath.Abs(src)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return gif.DecodeAll(r)
+}
@@ -0,0 +1,137 @@
+package steg
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// noisyGIF returns an animation of n frames of w x h, each of
+// random indices into a palette of greys. Every third frame
+// covers only the bottom right quarter of the animation.
+func noisyGIF(w, h, n int) *gif.GIF {
+
+	var p color.Palette
+	for i := 0; i < 64; i++ {
+		p = append(p, color.Gray{uint8(i * 4)})
+	}
+
+	g := &gif.GIF{Config: image.Config{ColorModel: p, Width: w, Height: h}}
+	r := rand.New(rand.NewSource(2))
+
+	for i := 0; i < n; i++ {
+		rect := image.Rect(0, 0, w, h)
+		if i%3 == 2 {
+			rect = image.Rect(w/2, h/2, w, h)
+		}
+		f := image.NewPaletted(rect, p)
+		for j := range f.Pix {
+			f.Pix[j] = uint8(r.Intn(len(p)))
+		}
+		g.Image = append(g.Image, f)
+		g.Delay = append(g.Delay, 10)
+	}
+
+	return g
+}
+
+// writeGIF saves g in a temporary directory and returns its path.
+func writeGIF(t *testing.T, g *gif.GIF) string {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), "src.gif")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err = gif.EncodeAll(f, g); err != nil {
+		t.Fatal(err)
+	}
+	return src
+}
+
+func TestEncodeGIF(t *testing.T) {
+
+	e, err := NewEncoder(WithChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := writeGIF(t, noisyGIF(24, 24, 5))
+	dst := dstPath(t, ".gif")
+
+	// Too long for the first two frames, so the quarter-sized
+	// third frame is used too.
+	msg := strings.Repeat("gif frames ", 15)
+	if err = e.EncodeGIF(src, dst, msg, Point{2, 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := e.DecodeGIF(dst, Point{2, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != msg {
+		t.Fatalf("got %q, want %q", got, msg)
+	}
+
+	g, err := readGIF(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Image) != 5 {
+		t.Fatalf("saved %d frames, want 5", len(g.Image))
+	}
+	for i, d := range g.Delay {
+		if d != 10 {
+			t.Fatalf("frame %d has delay %d, want 10", i, d)
+		}
+	}
+
+	// Swapping the first two frames puts the parts out of order.
+	g.Image[0], g.Image[1] = g.Image[1], g.Image[0]
+	if _, err = e.DecodeGIF(writeGIF(t, g), Point{2, 2}); err == nil {
+		t.Fatal("decoded a GIF with its frames reordered")
+	}
+
+	g.Image[0], g.Image[1] = g.Image[1], g.Image[0]
+	g.Image, g.Delay, g.Disposal = g.Image[:2], g.Delay[:2], g.Disposal[:2]
+	if _, err = e.DecodeGIF(writeGIF(t, g), Point{2, 2}); err == nil {
+		t.Fatal("decoded a GIF with a part missing")
+	}
+}
+
+func TestEncodeGIFErrors(t *testing.T) {
+
+	src := writeGIF(t, noisyGIF(16, 16, 2))
+
+	e, err := NewEncoder()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := dstPath(t, ".gif")
+	if err = e.EncodeGIF(src, dst, strings.Repeat("x", 100), Point{}); err == nil {
+		t.Fatal("EncodeGIF accepted a msg too large for its frames")
+	}
+	if _, err = os.Stat(dst); !os.IsNotExist(err) {
+		t.Fatalf("dst was saved despite the error: %v", err)
+	}
+
+	if _, err = e.DecodeGIF(src, Point{}); err == nil {
+		t.Fatal("decoded a GIF that holds no message")
+	}
+
+	e, err = NewEncoder(WithChannels(ChannelRed, ChannelGreen))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = e.EncodeGIF(src, dstPath(t, ".gif"), "hi", Point{}); err == nil {
+		t.Fatal("EncodeGIF accepted more than one channel")
+	}
+}
@@ -0,0 +1,143 @@
+package steg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+/*
+SetHeaderDepth specifies a bit depth (see SetBitDepth) for the
+magic marker and length header that is separate from the depth
+of the rest of the message. Packing the header more densely,
+for instance four bits to a channel, cuts the pixels it takes up
+without putting more bits than needed into the pixels of a long
+message, which matters most for short ones. The header then
+takes up pixels of its own and the rest of the message begins
+at the pixel after it. Messages must be decoded with the same
+header depth.
+
+If n is outside the range of 0-4 (inclusive) SetHeaderDepth
+returns an error. A depth of 0, the default, writes the header
+at the same depth as the rest of the message and in the same
+pixels. A separate header depth cannot be combined with
+SetBitHopping or a null terminator, and Capacity doesn't take it
+into account.
+*/
+func (e *Encoder) SetHeaderDepth(n int) error {
+	if n < 0 || n > 4 {
+		return fmt.Errorf("header depth out of bounds: got %d, wanted 0-4 inclusive", n)
+	}
+	e.headerDepth = n
+	return nil
+}
+
+func (e *Encoder) checkHeaderDepth() error {
+	if e.headerDepth == 0 {
+		return nil
+	}
+	if e.hopKey != "" || e.terminator {
+		return errors.New("header depth cannot be combined with bit hopping or a null terminator")
+	}
+	for _, c := range e.activeChannels() {
+		if bit := e.msgBit(c); !bitsFit(bit, e.headerDepth) {
+			return fmt.Errorf("msg bit %d with header depth %d runs past bit %d", bit, e.headerDepth, topBit(bit))
+		}
+	}
+	return nil
+}
+
+// header returns a copy of e that reads and writes at the
+// header depth.
+func (e *Encoder) header() *Encoder {
+	h := *e
+	h.depth = e.headerDepth
+	h.headerDepth = 0
+	return &h
+}
+
+// headerPixels returns how many pixels the first hdr bytes of
+// a payload take up.
+func (e *Encoder) headerPixels(hdr int) int {
+	if e.headerDepth == 0 {
+		return e.pixelsFor(hdr * e.byteBits())
+	}
+	return e.header().pixelsFor(hdr * e.byteBits())
+}
+
+// payloadPixels returns how many pixels a payload of size bytes
+// takes up, of which the first hdr bytes are header.
+func (e *Encoder) payloadPixels(size, hdr int) int {
+	if e.headerDepth == 0 {
+		return e.pixelsFor(size * e.byteBits())
+	}
+	return e.headerPixels(hdr) + e.pixelsFor((size-hdr)*e.byteBits())
+}
+
+/*
+writePayload is writeMsg for a payload whose first hdr bytes are
+header, writing them at the header depth when it is set.
+*/
+func (e *Encoder) writePayload(ctx context.Context, c carrier, pixels int, at func(int) Point, payload []byte, hdr int) (stats EncodeStats, err error) {
+
+	if e.headerDepth == 0 {
+		return e.writeMsg(ctx, c, pixels, at, payload)
+	}
+
+	hp := e.headerPixels(hdr)
+	stats, err = e.header().writeMsg(ctx, c, hp, at, payload[:hdr])
+	if err != nil {
+		return stats, err
+	}
+
+	body, err := e.writeMsg(ctx, c, pixels-hp, func(i int) Point {
+		return at(hp + i)
+	}, payload[hdr:])
+
+	stats.PixelsWritten += body.PixelsWritten
+	stats.PixelsChanged += body.PixelsChanged
+	stats.BitsFlipped += body.BitsFlipped
+
+	return stats, err
+}
+
+// payloadBytes returns how many bytes readPayload reads from
+// pixels pixels of a payload whose first hdr bytes are header.
+func (e *Encoder) payloadBytes(pixels, hdr int) int {
+	if e.headerDepth == 0 {
+		return pixels * e.bitsPerPixel() / e.byteBits()
+	}
+	hp := min(e.headerPixels(hdr), pixels)
+	return hdr + (pixels-hp)*e.bitsPerPixel()/e.byteBits()
+}
+
+// readPayload is the inverse of writePayload.
+func (e *Encoder) readPayload(ctx context.Context, c carrier, pixels int, at func(int) Point, hdr int) (data []byte, err error) {
+
+	if e.headerDepth == 0 {
+		return e.readMsg(ctx, c, pixels, at)
+	}
+
+	hp := e.headerPixels(hdr)
+	if hp > pixels {
+		hp = pixels
+	}
+
+	data, err = e.header().readMsg(ctx, c, hp, at)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := e.readMsg(ctx, c, pixels-hp, func(i int) Point {
+		return at(hp + i)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > hdr {
+		data = data[:hdr]
+	}
+
+	return append(data, body...), nil
+}
@@ -0,0 +1,45 @@
+package steg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Every message Encode accepts must decode, up to the longest
+// that fits.
+func TestHeaderDepthAtCapacity(t *testing.T) {
+
+	e, err := NewEncoder(WithLengthHeader(), WithHeaderDepth(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := Point{1, 1}
+
+	for n := 1; ; n++ {
+
+		img := noisyNRGBA(8, 8)
+		msg := strings.Repeat("m", n)
+
+		end, err := e.EncodeImage(img, msg, start)
+		if errors.Is(err, ErrMsgTooLarge) {
+			if n == 1 {
+				t.Fatal("no message fits")
+			}
+			break
+		}
+		if err != nil {
+			t.Fatalf("len %d: %v", n, err)
+		}
+
+		got, err := e.DecodeImage(img, start, end)
+		if err != nil || got != msg {
+			t.Fatalf("len %d: Decode: got %q, %v", n, got, err)
+		}
+		got, err = e.DecodeAutoImage(img, start)
+		if err != nil || got != msg {
+			t.Fatalf("len %d: DecodeAuto: got %q, %v", n, got, err)
+		}
+	}
+}
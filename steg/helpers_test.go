@@ -0,0 +1,43 @@
+package steg
+
+import (
+	"image"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// noisyNRGBA returns a w x h opaque image filled with noise that
+// is the same on every run.
+func noisyNRGBA(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	rand.New(rand.NewSource(1)).Read(img.Pix)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xff
+	}
+	return img
+}
+
+// writePNG saves img as a PNG in a temporary directory and
+// returns its path.
+func writePNG(t testing.TB, img image.Image) string {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), "src.png")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err = png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	return src
+}
+
+// dstPath returns a path with the given extension in a temporary
+// directory for an encoded image to be saved to.
+func dstPath(t testing.TB, ext string) string {
+	return filepath.Join(t.TempDir(), "dst"+ext)
+}
@@ -0,0 +1,38 @@
+package steg
+
+/*
+HideText writes text into the image at coverPath and saves the
+result to outPath, in the format given by its extension as for
+Encode. It is a shorthand for the common case where nothing but
+the message matters: text is written from the top left corner of
+the image to the least significant bit of the red channel with a
+length header and checksum, so that RevealText needs only the
+saved file to read it back.
+
+Text hidden with HideText can be read by any Encoder that has
+SetLengthHeader and SetChecksum enabled and is otherwise left at
+its defaults, using DecodeAuto from Point{}. For control over
+where and how text is written use an Encoder directly.
+*/
+func HideText(coverPath, outPath, text string) error {
+	_, err := textEncoder().Encode(coverPath, outPath, text, Point{})
+	return err
+}
+
+/*
+RevealText returns the text written to the image at stegoPath by
+HideText. An error is returned if the image holds no such text or
+it has been damaged.
+*/
+func RevealText(stegoPath string) (string, error) {
+	return textEncoder().DecodeAuto(stegoPath, Point{})
+}
+
+// textEncoder returns the encoder used by HideText and
+// RevealText.
+func textEncoder() *Encoder {
+	var e Encoder
+	e.SetLengthHeader(true)
+	e.SetChecksum(true)
+	return &e
+}
@@ -0,0 +1,51 @@
+package steg
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"testing"
+)
+
+func TestHideText(t *testing.T) {
+
+	const text = "nothing but the file"
+
+	out := dstPath(t, ".png")
+	if err := HideText(writePNG(t, noisyNRGBA(24, 24)), out, text); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := RevealText(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != text {
+		t.Fatalf("got %q, want %q", got, text)
+	}
+
+	// Any encoder with the documented settings can read it.
+	e, err := NewEncoder(WithLengthHeader(), WithChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err = e.DecodeAuto(out, Point{}); err != nil || got != text {
+		t.Fatalf("DecodeAuto gave %q, %v", got, err)
+	}
+
+	// Damaging the text is detected.
+	img, err := readImage(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	damaged := image.NewNRGBA(img.Bounds())
+	draw.Draw(damaged, damaged.Rect, img, image.Point{}, draw.Src)
+	damaged.Pix[4*20] ^= 1
+	if _, err = RevealText(writePNG(t, damaged)); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("got %v from damaged text, want ErrChecksumMismatch", err)
+	}
+
+	if err = HideText(writePNG(t, noisyNRGBA(4, 4)), dstPath(t, ".png"), text); !errors.Is(err, ErrMsgTooLarge) {
+		t.Fatalf("got %v, want ErrMsgTooLarge", err)
+	}
+}
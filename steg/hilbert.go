@@ -0,0 +1,155 @@
+package steg
+
+import "image"
+
+/*
+hilbertBlock is a block of pixels visited by the generalised
+Hilbert curve used for TraversalHilbert, which fills rectangles
+of any size rather than only squares whose sides are a power of
+two. The block begins at (x, y) and is w pixels along the major
+direction (dax, day) by h pixels along the minor direction
+(dbx, dby). Each block is split into two or three smaller ones,
+visited in turn, until it is a single row or column.
+*/
+type hilbertBlock struct {
+	x, y   int
+	ax, ay int
+	bx, by int
+}
+
+func (b hilbertBlock) size() (w, h int) {
+	return abs(b.ax + b.ay), abs(b.bx + b.by)
+}
+
+// split returns the blocks b is divided into, in the order
+// they are visited.
+func (b hilbertBlock) split() []hilbertBlock {
+
+	w, h := b.size()
+	dax, day := sign(b.ax), sign(b.ay)
+	dbx, dby := sign(b.bx), sign(b.by)
+
+	ax2, ay2 := floorHalf(b.ax), floorHalf(b.ay)
+	bx2, by2 := floorHalf(b.bx), floorHalf(b.by)
+	w2, h2 := abs(ax2+ay2), abs(bx2+by2)
+
+	if 2*w > 3*h {
+		// Long blocks are cut in two across their length,
+		// preferring halves of even length so the curve ends
+		// a step away from where the next half begins.
+		if w2%2 != 0 && w > 2 {
+			ax2, ay2 = ax2+dax, ay2+day
+		}
+		return []hilbertBlock{
+			{b.x, b.y, ax2, ay2, b.bx, b.by},
+			{b.x + ax2, b.y + ay2, b.ax - ax2, b.ay - ay2, b.bx, b.by},
+		}
+	}
+
+	if h2%2 != 0 && h > 2 {
+		bx2, by2 = bx2+dbx, by2+dby
+	}
+	return []hilbertBlock{
+		{b.x, b.y, bx2, by2, ax2, ay2},
+		{b.x + bx2, b.y + by2, b.ax, b.ay, b.bx - bx2, b.by - by2},
+		{
+			b.x + (b.ax - dax) + (bx2 - dbx), b.y + (b.ay - day) + (by2 - dby),
+			-bx2, -by2, -(b.ax - ax2), -(b.ay - ay2),
+		},
+	}
+}
+
+// contains reports whether p lies in b.
+func (b hilbertBlock) contains(p Point) bool {
+	w, h := b.size()
+	dx, dy := p.X-b.x, p.Y-b.y
+	i := dx*sign(b.ax) + dy*sign(b.ay)
+	j := dx*sign(b.bx) + dy*sign(b.by)
+	return 0 <= i && i < w && 0 <= j && j < h
+}
+
+// hilbertRoot returns the block covering all of r, with its
+// major direction along the longer side of r.
+func hilbertRoot(r image.Rectangle) hilbertBlock {
+	if r.Dx() >= r.Dy() {
+		return hilbertBlock{r.Min.X, r.Min.Y, r.Dx(), 0, 0, r.Dy()}
+	}
+	return hilbertBlock{r.Min.X, r.Min.Y, 0, r.Dy(), r.Dx(), 0}
+}
+
+// hilbertPoint returns the pixel of r at offset along the
+// curve.
+func hilbertPoint(r image.Rectangle, offset int) Point {
+
+	b := hilbertRoot(r)
+
+	for {
+		w, h := b.size()
+		switch {
+		case h == 1:
+			return Point{b.x + offset*sign(b.ax), b.y + offset*sign(b.ay)}
+		case w == 1:
+			return Point{b.x + offset*sign(b.bx), b.y + offset*sign(b.by)}
+		}
+
+		parts := b.split()
+		for i, part := range parts {
+			w, h := part.size()
+			if offset < w*h || i == len(parts)-1 {
+				b = part
+				break
+			}
+			offset -= w * h
+		}
+	}
+}
+
+// hilbertOffset is the inverse of hilbertPoint.
+func hilbertOffset(r image.Rectangle, p Point) int {
+
+	b := hilbertRoot(r)
+	offset := 0
+
+	for {
+		w, h := b.size()
+		dx, dy := p.X-b.x, p.Y-b.y
+		switch {
+		case h == 1:
+			return offset + dx*sign(b.ax) + dy*sign(b.ay)
+		case w == 1:
+			return offset + dx*sign(b.bx) + dy*sign(b.by)
+		}
+
+		parts := b.split()
+		for i, part := range parts {
+			if part.contains(p) || i == len(parts)-1 {
+				b = part
+				break
+			}
+			w, h := part.size()
+			offset += w * h
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// floorHalf returns n/2 rounded towards negative infinity.
+func floorHalf(n int) int {
+	return n >> 1
+}
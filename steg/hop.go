@@ -0,0 +1,81 @@
+package steg
+
+import (
+	"crypto/aes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+/*
+SetBitHopping makes the bit plane each byte of the message is
+written to change from byte to byte rather than always being
+the msg bit (see SetMsgBit). The plane of each byte is chosen
+from 0 to maxBit inclusive by a keystream derived from key, and
+the same key and maxBit must be used to decode the message.
+Planes above the least significant few are visible so maxBit
+should be kept low. Hopping requires a bit depth of 1 (see
+SetBitDepth).
+
+SetBitHopping returns an error if maxBit is outside the range
+of 0-7 (inclusive). An empty key disables hopping, which is the
+default.
+*/
+func (e *Encoder) SetBitHopping(key string, maxBit int) error {
+	if maxBit < 0 || maxBit > 7 {
+		return fmt.Errorf("max bit out of bounds: got %d, wanted 0-7 inclusive", maxBit)
+	}
+	e.hopKey = key
+	e.hopMax = maxBit
+	return nil
+}
+
+/*
+hops returns the bit planes for n bytes of message starting at
+byte from, or nil if hopping is disabled. The keystream is
+AES-CTR keyed by the SHA-256 hash of the hopping key, with one
+block for each byte of message so that the plane of any byte can
+be found without generating the planes before it. A byte's plane
+is the first byte of its block that falls below the largest
+multiple of hopMax+1 that fits in a byte, reduced modulo
+hopMax+1, so that every plane is equally likely; if none of them
+do, which is vanishingly rare, the block is encrypted again.
+*/
+func (e *Encoder) hops(from, n int) ([]byte, error) {
+
+	if e.hopKey == "" {
+		return nil, nil
+	}
+
+	key := sha256.Sum256([]byte(e.hopKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	planes := byte(e.hopMax + 1)
+	limit := 256 - 256%int(planes)
+
+	ks := make([]byte, n)
+	var buf [aes.BlockSize]byte
+
+	for i := range ks {
+		for round := uint64(0); ; round++ {
+
+			binary.BigEndian.PutUint64(buf[:8], round)
+			binary.BigEndian.PutUint64(buf[8:], uint64(from+i))
+			block.Encrypt(buf[:], buf[:])
+
+			j := 0
+			for j < len(buf) && int(buf[j]) >= limit {
+				j++
+			}
+			if j < len(buf) {
+				ks[i] = buf[j] % planes
+				break
+			}
+		}
+	}
+
+	return ks, nil
+}
@@ -0,0 +1,81 @@
+package steg
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHopsUniform(t *testing.T) {
+
+	// With 6 planes a plain modulo makes planes 4 and 5 about 1.5%
+	// less likely than the others.
+	for _, maxBit := range []int{0, 2, 5, 7} {
+
+		e, err := NewEncoder(WithBitHopping("key", maxBit))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		const n = 1 << 21
+		hops, err := e.hops(0, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		counts := make([]int, maxBit+1)
+		for _, h := range hops {
+			if int(h) > maxBit {
+				t.Fatalf("max bit %d: got plane %d", maxBit, h)
+			}
+			counts[h]++
+		}
+
+		want := n / (maxBit + 1)
+		for plane, c := range counts {
+			if c < want*99/100 || c > want*101/100 {
+				t.Fatalf("max bit %d: plane %d chosen %d times, want about %d", maxBit, plane, c, want)
+			}
+		}
+
+		// Any run of planes can be found without those before it.
+		part, err := e.hops(12345, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(part, hops[12345:12445]) {
+			t.Fatalf("max bit %d: planes from byte 12345 differ from those generated from 0", maxBit)
+		}
+	}
+}
+
+func TestBitHoppingRoundTrip(t *testing.T) {
+
+	const msg = "hopping between bit planes"
+
+	e, err := NewEncoder(WithBitHopping("key", 3), WithLengthHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := noisyNRGBA(32, 32)
+	end, err := e.EncodeImage(img, msg, Point{4, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := e.DecodeAutoImage(img, Point{4, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != msg {
+		t.Fatalf("got %q, want %q", got, msg)
+	}
+
+	other, err := NewEncoder(WithBitHopping("other key", 3), WithLengthHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := other.DecodeImage(img, Point{4, 1}, end); err == nil && got == msg {
+		t.Fatal("decoded the message with the wrong hopping key")
+	}
+}
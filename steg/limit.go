@@ -0,0 +1,36 @@
+package steg
+
+import "fmt"
+
+/*
+SetMaxDecodeBytes limits how much data decoding reads from an
+image to n bytes, so that services decoding untrusted images
+don't allocate however much an end point or length header asks
+for. The limit covers everything read from the image, including
+the magic marker, headers and checksum, and separately the
+message once decompressed. Decoding stops with an error wrapping
+ErrDecodeLimit before anything over the limit is allocated.
+
+Decode and the other functions taking an end point apply the
+limit to all of the data between start and end, which can
+exceed it even when the message itself is shorter, while
+DecodeAuto applies it to the length held by the length header.
+SetMaxDecodeBytes returns an error if n is negative. A limit of
+0, the default, means no limit.
+*/
+func (e *Encoder) SetMaxDecodeBytes(n int) error {
+	if n < 0 {
+		return fmt.Errorf("max decode bytes out of bounds: got %d, wanted 0 or more", n)
+	}
+	e.maxDecode = n
+	return nil
+}
+
+// checkDecodeLimit returns an error wrapping ErrDecodeLimit if
+// n bytes is over the limit set by SetMaxDecodeBytes.
+func (e *Encoder) checkDecodeLimit(n int) error {
+	if e.maxDecode > 0 && n > e.maxDecode {
+		return fmt.Errorf("%w: %d bytes, limit is %d", ErrDecodeLimit, n, e.maxDecode)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package steg
+
+import "time"
+
+/*
+SetLogger specifies a function that is called with structured
+events at key stages of encoding and decoding, so that they can
+be passed on to whatever logger the caller uses. Each event has
+a name and a map of fields:
+
+	capacity-computed  start, pixels and bytes available
+	encode-complete    start, end, msgLen, pixelsWritten,
+	                   pixelsChanged, bitsWritten and duration
+	decode-start       start, along with end and pixels when
+	                   they are known before reading the header
+	decode-complete    start, bytes and duration
+
+encode-complete and decode-complete also have an error field
+when they failed. Durations are time.Durations. Searches such as
+DecodeSearch and DecodeBruteforce log a decode for each place
+they try that holds a header. fn is called concurrently if the
+encoder is used by several goroutines at once. Passing nil
+disables logging, which is the default; no fields are built and
+no time is measured while it is disabled.
+*/
+func (e *Encoder) SetLogger(fn func(event string, fields map[string]any)) {
+	e.logger = fn
+}
+
+// logDecodeStart logs the start of a decode and returns the time
+// it began. It must only be called when a logger is set.
+func (e *Encoder) logDecodeStart(fields map[string]any) time.Time {
+	e.logger("decode-start", fields)
+	return time.Now()
+}
+
+// logDecodeDone logs the completion of a decode that began at t,
+// for deferring with pointers to its results. It must only be
+// called when a logger is set.
+func (e *Encoder) logDecodeDone(t time.Time, start Point, msg *[]byte, err *error) {
+	fields := map[string]any{
+		"start":    start,
+		"bytes":    len(*msg),
+		"duration": time.Since(t),
+	}
+	if *err != nil {
+		fields["error"] = *err
+	}
+	e.logger("decode-complete", fields)
+}
+
+// logCapacity logs the pixels and bytes available from start.
+func (e *Encoder) logCapacity(start Point, pixels, bytes int) {
+	if e.logger == nil {
+		return
+	}
+	e.logger("capacity-computed", map[string]any{
+		"start":  start,
+		"pixels": pixels,
+		"bytes":  bytes,
+	})
+}
+
+// logEncode logs the completion of an encode that began at t.
+func (e *Encoder) logEncode(t time.Time, start Point, stats EncodeStats, err error) {
+	if e.logger == nil {
+		return
+	}
+	fields := map[string]any{
+		"start":         start,
+		"end":           stats.End,
+		"msgLen":        stats.MsgLen,
+		"pixelsWritten": stats.PixelsWritten,
+		"pixelsChanged": stats.PixelsChanged,
+		"bitsWritten":   stats.BitsWritten,
+		"duration":      time.Since(t),
+	}
+	if err != nil {
+		fields["error"] = err
+	}
+	e.logger("encode-complete", fields)
+}
@@ -0,0 +1,90 @@
+package steg
+
+import (
+	"fmt"
+	"image"
+)
+
+/*
+LSBDiff compares bit plane bit of the images at paths a and b,
+typically a cover image and the same image with a message
+encoded into it, and returns an image the size of them showing
+where they differ. Bits are counted from the least significant
+as with SetMsgBit. Pixels whose bit is the same in every channel
+are black. Otherwise the pixel's red, green and blue are 255 for
+each of those channels whose bit differs, and the pixel is white
+if its alpha differs or the images have a single sample per
+pixel, as gray and paletted images do. The result shows which
+pixels a message was written to, and with SetScatterSeed or
+SetSpread how evenly it was spread over the image.
+
+An error is returned if the images have different bounds, either
+image is of a type Encode doesn't support, or bit is outside the
+samples of either image.
+*/
+func LSBDiff(a, b string, bit int) (image.Image, error) {
+
+	imgA, err := readImage(a)
+	if err != nil {
+		return nil, err
+	}
+
+	imgB, err := readImage(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return lsbDiff(imgA, imgB, bit)
+}
+
+func lsbDiff(a, b image.Image, bit int) (*image.NRGBA, error) {
+
+	if a.Bounds() != b.Bounds() {
+		return nil, fmt.Errorf("image bounds differ: got %v and %v", a.Bounds(), b.Bounds())
+	}
+
+	ca, err := newCarrier(a)
+	if err != nil {
+		return nil, err
+	}
+	cb, err := newCarrier(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if n := min(ca.sampleBits(), cb.sampleBits()); bit < 0 || bit >= n {
+		return nil, fmt.Errorf("bit out of bounds: got %d, wanted 0-%d inclusive", bit, n-1)
+	}
+
+	// Images with one sample per pixel are compared on it alone.
+	channels := []Channel{ChannelRed, ChannelGreen, ChannelBlue, ChannelAlpha}
+	single := ca.check(channels) != nil || cb.check(channels) != nil
+	if single {
+		channels = channels[:1]
+	}
+
+	bounds := a.Bounds()
+	diff := image.NewNRGBA(bounds)
+	mask := uint16(1) << uint(bit)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+
+			i := diff.PixOffset(x, y)
+			diff.Pix[i+3] = 255
+
+			for _, ch := range channels {
+				if (ca.sample(x, y, ch)^cb.sample(x, y, ch))&mask == 0 {
+					continue
+				}
+				if single || ch == ChannelAlpha {
+					diff.Pix[i], diff.Pix[i+1], diff.Pix[i+2] = 255, 255, 255
+					break
+				}
+				diff.Pix[i+int(ch)] = 255
+			}
+		}
+	}
+
+	return diff, nil
+}
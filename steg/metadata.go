@@ -0,0 +1,160 @@
+package steg
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"time"
+)
+
+// maxAuthorLen is the longest author Metadata can hold, as its
+// length is stored in a single byte.
+const maxAuthorLen = 255
+
+// metadataFixedSize is the size of the metadata header without
+// its author: the timestamp and the author's length.
+const metadataFixedSize = 8 + 1
+
+/*
+Metadata records where a message came from. It is written in a
+header before the message when enabled with SetMetadata and
+returned along with the message by DecodeMetadata. Time is
+stored to the second and Author may be at most 255 bytes long.
+*/
+type Metadata struct {
+	Time   time.Time
+	Author string
+}
+
+/*
+SetMetadata specifies metadata to write before each message, or
+disables the metadata header if md is nil, which is the default.
+If md.Time is zero the time of each encode is written instead.
+Messages written with metadata must be decoded by an encoder that
+also has it enabled, with any md, unless the format header (see
+SetFormatHeader) is used, which records whether the header is
+present. Decode returns just the message, while DecodeMetadata
+also returns the metadata.
+
+The header is the first part of the message, before compression,
+encryption and the checksum, so it is compressed, encrypted and
+checked along with it and counts towards the length header. Its
+layout is:
+
+	bytes 0-7    seconds since the Unix epoch, big-endian and signed
+	byte 8       length of the author in bytes, n
+	bytes 9-9+n  the author
+
+Because the timestamp may hold NUL bytes, metadata can only be
+combined with a null terminator if armor is enabled (see
+SetArmor). SetMetadata returns an error if md.Author is longer
+than 255 bytes.
+*/
+func (e *Encoder) SetMetadata(md *Metadata) error {
+	if md == nil {
+		e.metadata = nil
+		return nil
+	}
+	if len(md.Author) > maxAuthorLen {
+		return fmt.Errorf("author out of bounds: got %d bytes, wanted %d or fewer", len(md.Author), maxAuthorLen)
+	}
+	m := *md
+	e.metadata = &m
+	return nil
+}
+
+func (e *Encoder) checkMetadata() error {
+	if e.metadata != nil && e.terminator && e.armor == ArmorNone {
+		return errors.New("metadata cannot be combined with a null terminator unless armor is enabled")
+	}
+	return nil
+}
+
+// metadataSize returns the size of the metadata header, which is
+// zero when it is disabled.
+func (e *Encoder) metadataSize() int {
+	if e.metadata == nil {
+		return 0
+	}
+	return metadataFixedSize + len(e.metadata.Author)
+}
+
+// addMetadata returns msg preceded by the metadata header, if it
+// is enabled.
+func (e *Encoder) addMetadata(msg []byte) []byte {
+
+	if e.metadata == nil {
+		return msg
+	}
+
+	t := e.metadata.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	b := make([]byte, metadataFixedSize, e.metadataSize()+len(msg))
+	binary.BigEndian.PutUint64(b, uint64(t.Unix()))
+	b[8] = byte(len(e.metadata.Author))
+	b = append(b, e.metadata.Author...)
+
+	return append(b, msg...)
+}
+
+// stripMetadata removes the metadata header from the start of
+// msg, if it is enabled, storing it in e.decodedMetadata if that
+// is set.
+func (e *Encoder) stripMetadata(msg []byte) ([]byte, error) {
+
+	if e.metadata == nil {
+		return msg, nil
+	}
+
+	if len(msg) < metadataFixedSize {
+		return nil, errors.New("decoded data is too short to hold a metadata header")
+	}
+	n := metadataFixedSize + int(msg[8])
+	if len(msg) < n {
+		return nil, errors.New("decoded data is too short to hold a metadata header")
+	}
+
+	if e.decodedMetadata != nil {
+		*e.decodedMetadata = Metadata{
+			Time:   time.Unix(int64(binary.BigEndian.Uint64(msg)), 0).UTC(),
+			Author: string(msg[metadataFixedSize:n]),
+		}
+	}
+
+	return msg[n:], nil
+}
+
+/*
+DecodeMetadata is like Decode but also returns the metadata
+written before the message (see SetMetadata). Metadata's Time is
+in UTC. The metadata is zero if the message was written with a
+format header recording that it has none.
+*/
+func (e *Encoder) DecodeMetadata(src string, start, end Point) (msg string, md Metadata, err error) {
+
+	img, err := readImage(src)
+	if err != nil {
+		return msg, md, err
+	}
+
+	return e.DecodeImageMetadata(img, start, end)
+}
+
+// DecodeImageMetadata is like DecodeMetadata but reads msg
+// directly from img.
+func (e *Encoder) DecodeImageMetadata(img image.Image, start, end Point) (msg string, md Metadata, err error) {
+
+	if e.metadata == nil && !e.formatHeader {
+		return msg, md, errors.New("metadata is not enabled; see SetMetadata")
+	}
+
+	d := *e
+	d.decodedMetadata = &md
+	b, err := d.decodeImage(context.Background(), img, start, end)
+	return string(b), md, err
+}
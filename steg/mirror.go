@@ -0,0 +1,121 @@
+package steg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+)
+
+/*
+SetMirror specifies whether Encode writes a second copy of the
+message, so that it can still be recovered if the part of the
+image holding the first copy is painted over or otherwise
+changed. The first copy is written from start as usual and the
+second to the same number of pixels at the other end of the
+image, finishing just before its last pixel, so with row-major
+traversal the copies sit at the top and bottom of the image.
+Encode returns the end of the first copy and returns an error
+wrapping ErrMsgTooLarge if the copies would overlap.
+
+Decode reads the first copy and, if that fails, for instance
+with ErrChecksumMismatch, the second, returning the first
+copy's error if neither decodes. Nothing in the image records
+where the second copy is; it is located from the size of the
+image and the start and end of the first, so mirroring doesn't
+survive cropping. Once an image has been cropped the second copy
+can't be found, even if start and end are adjusted to match, and
+only the first can be decoded. Only Decode and the functions
+built on it fall back to the second copy, so DecodeAuto and
+DecodeUntilNull read just the first, and Capacity allows for
+both copies.
+
+Mirroring requires the checksum (see SetChecksum) so that a
+damaged copy can be recognised, and can't be combined with
+settings that choose which pixels are written to, namely spread,
+density, a pixel stride and skipping transparent pixels. It is
+disabled by default.
+*/
+func (e *Encoder) SetMirror(enabled bool) {
+	e.mirror = enabled
+}
+
+func (e *Encoder) checkMirror() error {
+	if !e.mirror {
+		return nil
+	}
+	if !e.checksum {
+		return errors.New("mirror requires the checksum to be enabled")
+	}
+	if e.spread || e.densityWindow != 0 || e.stride != 0 || e.skipTransparent {
+		return errors.New("mirror cannot be combined with spread, density, a pixel stride or skipping transparent pixels")
+	}
+	return nil
+}
+
+/*
+mirrorStart returns the start and end points of the second copy
+of a message whose first copy is written from start to end, so
+that it finishes just before the last pixel of bounds. An error
+wrapping ErrMsgTooLarge is returned if the copies overlap.
+*/
+func (e *Encoder) mirrorStart(bounds image.Rectangle, start, end Point) (Point, Point, error) {
+
+	first := offsetFromMin(bounds, e.traversal, start)
+	last := offsetFromMin(bounds, e.traversal, end)
+	total := bounds.Dx() * bounds.Dy()
+
+	second := total - 1 - (last - first)
+	if second < last {
+		return start, end, fmt.Errorf("%w: two copies of msg need %d pixels but only %d are available from start",
+			ErrMsgTooLarge, 2*(last-first), total-1-first)
+	}
+
+	return pointAt(bounds, e.traversal, second), pointAt(bounds, e.traversal, total-1), nil
+}
+
+// encodeMirror writes the second copy of msg, whose first copy
+// was placed at p, returning the stats of both copies together.
+func (e *Encoder) encodeMirror(ctx context.Context, c carrier, msg []byte, p placement, stats EncodeStats) (EncodeStats, error) {
+
+	start, _, err := e.mirrorStart(c.bounds(), p.start, p.end)
+	if err != nil {
+		return stats, err
+	}
+
+	d := *e
+	d.mirror = false
+	d.logger = nil
+
+	_, second, err := d.encodePlaced(ctx, c, msg, start)
+	stats.PixelsWritten += second.PixelsWritten
+	stats.PixelsChanged += second.PixelsChanged
+	stats.BitsFlipped += second.BitsFlipped
+	stats.BitsWritten += second.BitsWritten
+	stats.CapacityUsed = float64(stats.BitsWritten) / float64(stats.CapacityBits)
+
+	return stats, err
+}
+
+// decodeMirror is decodeCarrier for an encoder with mirroring
+// enabled.
+func (e *Encoder) decodeMirror(ctx context.Context, c carrier, start, end Point) (msg []byte, err error) {
+
+	d := *e
+	d.mirror = false
+
+	msg, err = d.decodeCarrier(ctx, c, start, end)
+	if err == nil {
+		return msg, nil
+	}
+
+	s, t, mirrorErr := d.mirrorStart(c.bounds(), start, end)
+	if mirrorErr != nil || !inBounds(c.bounds(), start) || !inBounds(c.bounds(), end) {
+		return msg, err
+	}
+	if second, secondErr := d.decodeCarrier(ctx, c, s, t); secondErr == nil {
+		return second, nil
+	}
+
+	return msg, err
+}
@@ -0,0 +1,115 @@
+package steg
+
+import (
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestMirror(t *testing.T) {
+
+	e, err := NewEncoder(WithLengthHeader(), WithChecksum(), WithMirror())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const msg = "written twice so that it survives damage to either copy"
+	img := noisyNRGBA(40, 40)
+	end, err := e.EncodeImage(img, msg, Point{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Paint over the top rows, which hold the first copy.
+	damaged := copyNRGBA(img)
+	for i := 0; i < (end.Y+1)*damaged.Stride; i++ {
+		damaged.Pix[i] = 0
+	}
+	got, err := e.DecodeImage(damaged, Point{}, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != msg {
+		t.Fatalf("got %q from the second copy, want %q", got, msg)
+	}
+
+	// Paint over the bottom rows too.
+	for i := len(damaged.Pix) - (end.Y+2)*damaged.Stride; i < len(damaged.Pix); i++ {
+		damaged.Pix[i] = 0
+	}
+	if _, err = e.DecodeImage(damaged, Point{}, end); err == nil {
+		t.Fatal("decoded with both copies damaged")
+	}
+}
+
+func TestMirrorCropped(t *testing.T) {
+
+	e, err := NewEncoder(WithLengthHeader(), WithChecksum(), WithMirror())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const msg = "the second copy is found from the size of the image"
+	img := noisyNRGBA(40, 40)
+	end, err := e.EncodeImage(img, msg, Point{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cropping a column off the right moves where the second
+	// copy is expected, so only the first can be decoded.
+	cropped := img.SubImage(image.Rect(0, 0, 39, 40)).(*image.NRGBA)
+	if _, err = e.DecodeImage(copyNRGBA(cropped), Point{}, end); err == nil {
+		t.Fatal("decoded a copy that spans the cropped column")
+	}
+
+	cropped = img.SubImage(image.Rect(0, 0, 40, 39)).(*image.NRGBA)
+	got, err := e.DecodeImage(cropped, Point{}, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != msg {
+		t.Fatalf("got %q, want %q", got, msg)
+	}
+
+	damaged := copyNRGBA(cropped)
+	for i := 0; i < (end.Y+1)*damaged.Stride; i++ {
+		damaged.Pix[i] = 0
+	}
+	if _, err = e.DecodeImage(damaged, Point{}, end); err == nil {
+		t.Fatal("decoded a cropped image with the first copy damaged")
+	}
+}
+
+func TestMirrorTooLarge(t *testing.T) {
+
+	e, err := NewEncoder(WithLengthHeader(), WithChecksum(), WithMirror())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One copy fits but two don't.
+	img := noisyNRGBA(16, 16)
+	msg := make([]byte, 20)
+	if _, err = e.EncodeImage(img, string(msg), Point{}); !errors.Is(err, ErrMsgTooLarge) {
+		t.Fatalf("got %v, want ErrMsgTooLarge", err)
+	}
+}
+
+func TestMirrorSettings(t *testing.T) {
+
+	if _, err := NewEncoder(WithLengthHeader(), WithMirror()); err == nil {
+		t.Error("mirror was accepted without the checksum")
+	}
+
+	for name, opt := range map[string]Option{
+		"spread":           WithSpread(),
+		"density":          WithDensity(0.5, 3),
+		"stride":           WithPixelStride(2, 0),
+		"skip transparent": WithSkipTransparent(),
+	} {
+		if _, err := NewEncoder(WithLengthHeader(), WithChecksum(), WithMirror(), opt); err == nil {
+			t.Errorf("mirror was accepted with %s", name)
+		}
+	}
+}
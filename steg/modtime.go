@@ -0,0 +1,33 @@
+package steg
+
+import "os"
+
+/*
+SetPreserveModTime specifies whether Encode and the other methods
+that save an image copied from src give dst the modification
+time of src once it has been written, so that dst doesn't stand
+out as newly created. The access time of dst is set to the same
+time: the access time of src can't be read portably and will
+usually have just been updated by reading src anyway. Methods
+such as EncodeNewImage that have no src are unaffected. It is
+disabled by default.
+*/
+func (e *Encoder) SetPreserveModTime(enabled bool) {
+	e.preserveModTime = enabled
+}
+
+// keepModTime gives dst the modification time of src if
+// enabled.
+func (e *Encoder) keepModTime(src, dst string) error {
+
+	if !e.preserveModTime {
+		return nil
+	}
+
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	return os.Chtimes(dst, fi.ModTime(), fi.ModTime())
+}
@@ -0,0 +1,121 @@
+package steg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+)
+
+/*
+SetNullTerminator specifies whether Encode follows msg with a
+0x00 byte, as with a C string, so that it can be read back with
+DecodeUntilNull without knowing where it ends. While enabled msg
+must not itself contain a 0x00 byte.
+
+The terminator is found by looking for the first zero byte so it
+cannot be combined with settings whose output may contain one:
+compression, encryption, checksums and the length header. Nor
+can it be combined with SetScatterSeed, since the pixels a
+scattered message was written to depend on its length. It is
+disabled by default.
+*/
+func (e *Encoder) SetNullTerminator(enabled bool) {
+	e.terminator = enabled
+}
+
+func (e *Encoder) checkTerminator() error {
+	if !e.terminator {
+		return nil
+	}
+	if e.compression || e.passphrase != "" || e.checksum || e.lengthHeader {
+		return errors.New("null terminator cannot be combined with compression, encryption, checksums or the length header")
+	}
+	if e.scatter {
+		return errors.New("null terminator cannot be combined with scatter")
+	}
+	return nil
+}
+
+/*
+DecodeUntilNull reads src from start until it finds the 0x00
+byte written after msg by an encoder with a null terminator (see
+SetNullTerminator), which must also be enabled on e. The
+terminator is not included in msg. ErrNoTerminator is returned
+if the end of the image is reached first.
+*/
+func (e *Encoder) DecodeUntilNull(src string, start Point) (msg string, err error) {
+
+	img, err := readImage(src)
+	if err != nil {
+		return msg, err
+	}
+
+	if !e.terminator {
+		return msg, errors.New("DecodeUntilNull requires a null terminator; see SetNullTerminator")
+	}
+
+	c, err := e.carrierFor(img)
+	if err != nil {
+		return msg, err
+	}
+
+	b, err := e.decodeUntilNull(context.Background(), c, start)
+	return string(b), err
+}
+
+// nullBlock is how many pixels DecodeUntilNull reads at a time.
+// A multiple of 8 keeps every block byte aligned.
+const nullBlock = 8 * 512
+
+func (e *Encoder) decodeUntilNull(ctx context.Context, c carrier, start Point) (msg []byte, err error) {
+
+	bounds := c.bounds()
+	if !inBounds(bounds, start) {
+		return msg, ErrStartOutOfBounds
+	}
+
+	if e.logger != nil {
+		t := e.logDecodeStart(map[string]any{"start": start})
+		defer e.logDecodeDone(t, start, &msg, &err)
+	}
+
+	first := offsetFromMin(bounds, e.traversal, start)
+	remaining := bounds.Dx()*bounds.Dy() - first
+
+	var data []byte
+
+	for done := 0; done < remaining; done += nullBlock {
+
+		pixels := nullBlock
+		if remaining-done < pixels {
+			pixels = remaining - done
+		}
+
+		offset := first + done
+		b, err := e.readMsgFrom(ctx, c, pixels, func(i int) Point {
+			return pointAt(bounds, e.traversal, offset+i)
+		}, len(data))
+		if err != nil {
+			return msg, err
+		}
+		data = append(data, b...)
+
+		if len(data) < len(e.magic) {
+			continue
+		}
+		if err = e.checkMagic(data); err != nil {
+			return msg, err
+		}
+		if i := bytes.IndexByte(data[len(e.magic):], 0); i >= 0 {
+			if err = e.checkDecodeLimit(len(e.magic) + i + 1); err != nil {
+				return msg, err
+			}
+			return e.unframe(data)
+		}
+		if err = e.checkDecodeLimit(len(data)); err != nil {
+			return msg, err
+		}
+	}
+
+	return msg, ErrNoTerminator
+}
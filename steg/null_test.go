@@ -0,0 +1,83 @@
+package steg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeUntilNull(t *testing.T) {
+
+	for _, tt := range []struct {
+		opts []Option
+		msg  string
+	}{
+		{[]Option{WithNullTerminator()}, "no end point needed"},
+		{[]Option{WithNullTerminator(), WithMagic([]byte("NUL"))}, "no end point needed"},
+
+		// Long enough to be read over several blocks.
+		{[]Option{WithNullTerminator(), WithChannels(ChannelRed, ChannelGreen, ChannelBlue)}, strings.Repeat("block ", 400)},
+	} {
+
+		e, err := NewEncoder(tt.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dst := dstPath(t, ".png")
+		if _, err = e.Encode(writePNG(t, noisyNRGBA(100, 100)), dst, tt.msg, Point{3, 4}); err != nil {
+			t.Fatalf("%s: %v", e, err)
+		}
+
+		got, err := e.DecodeUntilNull(dst, Point{3, 4})
+		if err != nil {
+			t.Fatalf("%s: %v", e, err)
+		}
+		if got != tt.msg {
+			t.Fatalf("%s: got %q, want %q", e, got, tt.msg)
+		}
+	}
+}
+
+func TestDecodeUntilNullErrors(t *testing.T) {
+
+	e, err := NewEncoder(WithNullTerminator())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := writePNG(t, noisyNRGBA(16, 16))
+	if _, err = e.Encode(src, dstPath(t, ".png"), "nul\x00byte", Point{}); err == nil {
+		t.Fatal("Encode accepted a msg containing a NUL byte")
+	}
+
+	// Setting every bit read means no byte is ever zero.
+	img := noisyNRGBA(16, 16)
+	for i := range img.Pix {
+		img.Pix[i] |= 1
+	}
+	if _, err = e.DecodeUntilNull(writePNG(t, img), Point{}); !errors.Is(err, ErrNoTerminator) {
+		t.Fatalf("got %v, want ErrNoTerminator", err)
+	}
+
+	plain, err := NewEncoder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = plain.DecodeUntilNull(src, Point{}); err == nil {
+		t.Fatal("DecodeUntilNull succeeded without a null terminator")
+	}
+
+	for name, opt := range map[string]Option{
+		"compression":       WithCompression(),
+		"encryption":        WithPassphrase("pw"),
+		"a checksum":        WithChecksum(),
+		"the length header": WithLengthHeader(),
+		"scatter":           WithScatterSeed(1),
+		"a header depth":    WithHeaderDepth(2),
+	} {
+		if _, err := NewEncoder(WithNullTerminator(), opt); err == nil {
+			t.Errorf("null terminator was accepted with %s", name)
+		}
+	}
+}
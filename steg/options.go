@@ -0,0 +1,346 @@
+package steg
+
+import "image/png"
+
+/*
+Option configures an Encoder created by NewEncoder. Each option
+corresponds to one of the Encoder's Set methods.
+*/
+type Option func(e *Encoder) error
+
+/*
+NewEncoder returns an Encoder configured by opts, applied in the
+order given. It returns the first error reported by an option,
+or an error if the options are valid on their own but can't be
+used together, so that the settings need only be checked once.
+The Set methods can still be used on the returned Encoder.
+*/
+func NewEncoder(opts ...Option) (*Encoder, error) {
+
+	e := new(Encoder)
+
+	for _, opt := range opts {
+		if err := opt(e); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := e.checkSettings(); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+/*
+Clone returns a copy of e that can have its settings changed
+without affecting e, for instance to derive encoders that differ
+only in passphrase from a shared base.
+*/
+func (e *Encoder) Clone() *Encoder {
+	c := *e
+	c.channels = append([]Channel(nil), e.channels...)
+	c.magic = append([]byte(nil), e.magic...)
+	if e.channelBits != nil {
+		c.channelBits = make(map[Channel]int, len(e.channelBits))
+		for ch, n := range e.channelBits {
+			c.channelBits[ch] = n
+		}
+	}
+	return &c
+}
+
+// WithBit is the Option form of SetMsgBit.
+func WithBit(n int) Option {
+	return func(e *Encoder) error {
+		return e.SetMsgBit(n)
+	}
+}
+
+// WithChannel is the Option form of SetChannel.
+func WithChannel(c Channel) Option {
+	return func(e *Encoder) error {
+		return e.SetChannel(c)
+	}
+}
+
+// WithChannels is the Option form of SetChannels.
+func WithChannels(cs ...Channel) Option {
+	return func(e *Encoder) error {
+		return e.SetChannels(cs)
+	}
+}
+
+// WithChannelBits is the Option form of SetChannelBits.
+func WithChannelBits(bits map[Channel]int) Option {
+	return func(e *Encoder) error {
+		return e.SetChannelBits(bits)
+	}
+}
+
+// WithBitDepth is the Option form of SetBitDepth.
+func WithBitDepth(n int) Option {
+	return func(e *Encoder) error {
+		return e.SetBitDepth(n)
+	}
+}
+
+// WithFormatHeader is the Option form of SetFormatHeader.
+func WithFormatHeader(enabled bool) Option {
+	return func(e *Encoder) error {
+		e.SetFormatHeader(enabled)
+		return nil
+	}
+}
+
+// WithHeaderDepth is the Option form of SetHeaderDepth.
+func WithHeaderDepth(n int) Option {
+	return func(e *Encoder) error {
+		return e.SetHeaderDepth(n)
+	}
+}
+
+// WithChroma enables chroma mode; see SetChroma.
+func WithChroma() Option {
+	return func(e *Encoder) error {
+		e.SetChroma(true)
+		return nil
+	}
+}
+
+// WithBitHopping is the Option form of SetBitHopping.
+func WithBitHopping(key string, maxBit int) Option {
+	return func(e *Encoder) error {
+		return e.SetBitHopping(key, maxBit)
+	}
+}
+
+// WithLengthHeader enables the length header; see
+// SetLengthHeader.
+func WithLengthHeader() Option {
+	return func(e *Encoder) error {
+		e.SetLengthHeader(true)
+		return nil
+	}
+}
+
+// WithMagic is the Option form of SetMagic.
+func WithMagic(marker []byte) Option {
+	return func(e *Encoder) error {
+		e.SetMagic(marker)
+		return nil
+	}
+}
+
+// WithPassphrase is the Option form of SetPassphrase.
+func WithPassphrase(pw string) Option {
+	return func(e *Encoder) error {
+		e.SetPassphrase(pw)
+		return nil
+	}
+}
+
+// WithCompression enables compression; see SetCompression.
+func WithCompression() Option {
+	return func(e *Encoder) error {
+		e.SetCompression(true)
+		return nil
+	}
+}
+
+// WithArmor is the Option form of SetArmor.
+func WithArmor(a Armor) Option {
+	return func(e *Encoder) error {
+		return e.SetArmor(a)
+	}
+}
+
+// WithMetadata is the Option form of SetMetadata.
+func WithMetadata(md *Metadata) Option {
+	return func(e *Encoder) error {
+		return e.SetMetadata(md)
+	}
+}
+
+// WithMirror enables mirroring; see SetMirror.
+func WithMirror() Option {
+	return func(e *Encoder) error {
+		e.SetMirror(true)
+		return nil
+	}
+}
+
+// WithChecksum enables the checksum; see SetChecksum.
+func WithChecksum() Option {
+	return func(e *Encoder) error {
+		e.SetChecksum(true)
+		return nil
+	}
+}
+
+// WithTraversal is the Option form of SetTraversal.
+func WithTraversal(t Traversal) Option {
+	return func(e *Encoder) error {
+		return e.SetTraversal(t)
+	}
+}
+
+// WithBitOrder is the Option form of SetBitOrder.
+func WithBitOrder(order BitOrder) Option {
+	return func(e *Encoder) error {
+		return e.SetBitOrder(order)
+	}
+}
+
+// WithScatterSeed is the Option form of SetScatterSeed.
+func WithScatterSeed(seed int64) Option {
+	return func(e *Encoder) error {
+		e.SetScatterSeed(seed)
+		return nil
+	}
+}
+
+// WithSpread enables spreading; see SetSpread.
+func WithSpread() Option {
+	return func(e *Encoder) error {
+		e.SetSpread(true)
+		return nil
+	}
+}
+
+// WithPixelStride is the Option form of SetPixelStride.
+func WithPixelStride(stride, phase int) Option {
+	return func(e *Encoder) error {
+		return e.SetPixelStride(stride, phase)
+	}
+}
+
+// WithDensity is the Option form of SetDensity.
+func WithDensity(threshold float64, window int) Option {
+	return func(e *Encoder) error {
+		return e.SetDensity(threshold, window)
+	}
+}
+
+// WithSkipTransparent enables skipping transparent pixels; see
+// SetSkipTransparent.
+func WithSkipTransparent() Option {
+	return func(e *Encoder) error {
+		e.SetSkipTransparent(true)
+		return nil
+	}
+}
+
+// WithErrorCorrection is the Option form of SetErrorCorrection.
+func WithErrorCorrection(ecc ErrorCorrection) Option {
+	return func(e *Encoder) error {
+		return e.SetErrorCorrection(ecc)
+	}
+}
+
+// WithParity enables a parity bit per pixel; see SetParity.
+func WithParity() Option {
+	return func(e *Encoder) error {
+		e.SetParity(true)
+		return nil
+	}
+}
+
+// WithMaxDecodeBytes is the Option form of SetMaxDecodeBytes.
+func WithMaxDecodeBytes(n int) Option {
+	return func(e *Encoder) error {
+		return e.SetMaxDecodeBytes(n)
+	}
+}
+
+// WithVerifyOnDisk enables on-disk verification; see
+// SetVerifyOnDisk.
+func WithVerifyOnDisk() Option {
+	return func(e *Encoder) error {
+		e.SetVerifyOnDisk(true)
+		return nil
+	}
+}
+
+// WithSidecar enables sidecar files; see SetSidecar.
+func WithSidecar() Option {
+	return func(e *Encoder) error {
+		e.SetSidecar(true)
+		return nil
+	}
+}
+
+// WithNullTerminator enables the null terminator; see
+// SetNullTerminator.
+func WithNullTerminator() Option {
+	return func(e *Encoder) error {
+		e.SetNullTerminator(true)
+		return nil
+	}
+}
+
+// WithCreateDirs enables creating dst directories; see
+// SetCreateDirs.
+func WithCreateDirs() Option {
+	return func(e *Encoder) error {
+		e.SetCreateDirs(true)
+		return nil
+	}
+}
+
+// WithPNGChunks enables copying PNG metadata; see
+// SetPNGChunks.
+func WithPNGChunks() Option {
+	return func(e *Encoder) error {
+		e.SetPNGChunks(true)
+		return nil
+	}
+}
+
+// WithPreserveFormat enables keeping the PNG colour type of src;
+// see SetPreserveFormat.
+func WithPreserveFormat() Option {
+	return func(e *Encoder) error {
+		e.SetPreserveFormat(true)
+		return nil
+	}
+}
+
+// WithPreserveModTime enables copying the modification time of
+// src to dst; see SetPreserveModTime.
+func WithPreserveModTime() Option {
+	return func(e *Encoder) error {
+		e.SetPreserveModTime(true)
+		return nil
+	}
+}
+
+// WithPNGCompression is the Option form of SetPNGCompression.
+func WithPNGCompression(level png.CompressionLevel) Option {
+	return func(e *Encoder) error {
+		return e.SetPNGCompression(level)
+	}
+}
+
+// WithProgress is the Option form of SetProgress.
+func WithProgress(fn func(done, total int)) Option {
+	return func(e *Encoder) error {
+		e.SetProgress(fn)
+		return nil
+	}
+}
+
+// WithParallelism is the Option form of SetParallelism.
+func WithParallelism(n int) Option {
+	return func(e *Encoder) error {
+		return e.SetParallelism(n)
+	}
+}
+
+// WithLogger is the Option form of SetLogger.
+func WithLogger(fn func(event string, fields map[string]any)) Option {
+	return func(e *Encoder) error {
+		e.SetLogger(fn)
+		return nil
+	}
+}
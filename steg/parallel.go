@@ -0,0 +1,114 @@
+package steg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// parallelBand is how many pixels each goroutine writes at a
+// time when encoding in parallel. Messages shorter than two
+// bands are always written on the calling goroutine.
+const parallelBand = 4 * ctxCheckInterval
+
+/*
+SetParallelism specifies how many goroutines share the work of
+writing a message to the image. The pixels the message is
+written to are split into bands of consecutive pixels, which in
+row-major order are horizontal bands of the image, and handed
+out to n goroutines that each write the bits belonging to their
+band. Every pixel is written by exactly one goroutine so the
+result is the same as writing them all in turn. Messages too
+short to be worth splitting, and paletted images, whose palette
+may have to grow to hold them (see EncodeImage), are still
+written on the calling goroutine. Progress reported with
+SetProgress is made once for each band as it is finished rather
+than every 1%.
+
+SetParallelism returns an error if n is negative. An n of 0 or 1
+writes the message on the calling goroutine, which is the
+default.
+*/
+func (e *Encoder) SetParallelism(n int) error {
+	if n < 0 {
+		return fmt.Errorf("parallelism out of bounds: got %d, wanted 0 or more", n)
+	}
+	e.parallelism = n
+	return nil
+}
+
+// parallel reports whether a message taking the given number of
+// pixels is written to c in parallel.
+func (e *Encoder) parallel(c carrier, pixels int) bool {
+	return e.parallelism > 1 && pixels >= 2*parallelBand && concurrentSafe(c)
+}
+
+// concurrentSafe reports whether separate pixels of c can be
+// written to by different goroutines at once.
+func concurrentSafe(c carrier) bool {
+	switch c := c.(type) {
+	case regionCarrier:
+		return concurrentSafe(c.carrier)
+	case chromaCarrier:
+		return concurrentSafe(c.carrier)
+	case palettedCarrier:
+		return false
+	}
+	return true
+}
+
+// writeParallel is writeMsg for a message written by a pool of
+// e.parallelism goroutines, reporting progress to r.
+func (e *Encoder) writeParallel(ctx context.Context, c carrier, pixels int, at func(int) Point, msg, hops []byte, r *reporter) (stats EncodeStats, err error) {
+
+	bands := (pixels + parallelBand - 1) / parallelBand
+	total := len(msg) * e.byteBits()
+	bpp := e.bitsPerPixel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	done := 0
+
+	jobs := make(chan int)
+	for w := 0; w < min(e.parallelism, bands); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range jobs {
+
+				lo := b * parallelBand
+				hi := min(lo+parallelBand, pixels)
+				band, bandErr := e.writeBand(ctx, c, lo, hi, at, msg, hops, nil)
+
+				mu.Lock()
+				stats.PixelsWritten += band.PixelsWritten
+				stats.PixelsChanged += band.PixelsChanged
+				stats.BitsFlipped += band.BitsFlipped
+				if bandErr != nil && err == nil {
+					err = bandErr
+				}
+				done += (hi - lo) * bpp
+				r.report(min(done, total))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	sent := 0
+send:
+	for ; sent < bands; sent++ {
+		select {
+		case jobs <- sent:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err == nil && sent < bands {
+		err = ctx.Err()
+	}
+
+	return stats, err
+}
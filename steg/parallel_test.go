@@ -0,0 +1,75 @@
+package steg
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestParallelMatchesSerial(t *testing.T) {
+
+	msg := make([]byte, 12000)
+	rand.New(rand.NewSource(2)).Read(msg)
+
+	for _, opts := range [][]Option{
+		{WithLengthHeader()},
+		{WithLengthHeader(), WithChannels(ChannelRed, ChannelGreen, ChannelBlue), WithBitDepth(2)},
+		{WithLengthHeader(), WithBitHopping("key", 3)},
+		{WithLengthHeader(), WithErrorCorrection(ECCHamming74)},
+		{WithLengthHeader(), WithScatterSeed(7)},
+	} {
+
+		serial, err := NewEncoder(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parallel, err := NewEncoder(append(opts, WithParallelism(4))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := noisyNRGBA(512, 512)
+		wantStats, err := serial.EncodeImageWithStats(want, string(msg), Point{3, 1})
+		if err != nil {
+			t.Fatalf("%s: %v", serial, err)
+		}
+
+		got := noisyNRGBA(512, 512)
+		gotStats, err := parallel.EncodeImageWithStats(got, string(msg), Point{3, 1})
+		if err != nil {
+			t.Fatalf("%s: %v", parallel, err)
+		}
+
+		if !bytes.Equal(got.Pix, want.Pix) {
+			t.Fatalf("%s: parallel encode wrote different pixels to serial", parallel)
+		}
+		if gotStats != wantStats {
+			t.Fatalf("%s: got stats %+v, want %+v", parallel, gotStats, wantStats)
+		}
+	}
+}
+
+func BenchmarkEncodeParallel(b *testing.B) {
+
+	img := noisyNRGBA(3840, 2160)
+	msg := make([]byte, 512<<10)
+	rand.New(rand.NewSource(2)).Read(msg)
+
+	for _, n := range []int{1, 2, 4, 8} {
+
+		e, err := NewEncoder(WithLengthHeader(), WithParallelism(n))
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(fmt.Sprintf("goroutines=%d", n), func(b *testing.B) {
+			b.SetBytes(int64(len(msg)))
+			for i := 0; i < b.N; i++ {
+				if _, err := e.EncodeImage(img, string(msg), Point{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package steg
+
+import (
+	"context"
+	"errors"
+	"image"
+)
+
+// parityChannels are the channels message bits are written to
+// in parity mode.
+var parityChannels = []Channel{ChannelRed, ChannelGreen}
+
+/*
+SetParity specifies whether each pixel holds two bits of the
+message, in its red and green channels, along with a parity bit
+in its blue channel that is the exclusive or of the two. All
+three bits are written to the msg bit (see SetMsgBit), and the
+channels set with SetChannel or SetChannels are ignored while
+parity is enabled.
+
+Decoding reads the message bits as usual, while DecodeParity
+also checks the parity bit of each pixel read and reports how
+many pixels fail, locating damage to the image without the
+overhead of a checksum over the whole message. A single parity
+bit can only detect that one of a pixel's bits has changed, not
+which; combined with SetErrorCorrection the changed bits are
+usually corrected as well. Changing the blue channel for parity
+makes the message slightly easier to detect.
+
+Parity cannot be combined with SetChroma, SetChannelBits,
+SetBitHopping, SetHeaderDepth or a bit depth above 1. It is
+disabled by default and is not recorded by the format header.
+*/
+func (e *Encoder) SetParity(enabled bool) {
+	e.parity = enabled
+}
+
+func (e *Encoder) checkParity() error {
+	if !e.parity {
+		return nil
+	}
+	if e.chroma || e.channelBits != nil || e.hopKey != "" || e.headerDepth != 0 || e.bitDepth() > 1 {
+		return errors.New("parity cannot be combined with chroma, channel bits, bit hopping, a header depth or a bit depth above 1")
+	}
+	return nil
+}
+
+// setParity sets the parity bit of the pixel at p from its
+// message bits, reporting whether it changed.
+func (e *Encoder) setParity(c carrier, p Point) bool {
+	plane := uint(e.bit)
+	r := c.sample(p.X, p.Y, ChannelRed) >> plane & 1
+	g := c.sample(p.X, p.Y, ChannelGreen) >> plane & 1
+	old := c.sample(p.X, p.Y, ChannelBlue)
+	v := old&^(1<<plane) | (r^g)<<plane
+	c.setSample(p.X, p.Y, ChannelBlue, v)
+	return v != old
+}
+
+// verifyParity records p in e.corrupt if its parity bit doesn't
+// match its message bits.
+func (e *Encoder) verifyParity(c carrier, p Point) {
+	plane := uint(e.bit)
+	r := c.sample(p.X, p.Y, ChannelRed) >> plane & 1
+	g := c.sample(p.X, p.Y, ChannelGreen) >> plane & 1
+	b := c.sample(p.X, p.Y, ChannelBlue) >> plane & 1
+	if r^g != b {
+		e.corrupt[p] = true
+	}
+}
+
+/*
+DecodeParity is like Decode but also returns how many of the
+pixels read fail their parity check (see SetParity), each of
+which has had at least one of its bits changed since encoding.
+Pixels read more than once, as some settings need, are counted
+once. It returns an error if parity is not enabled.
+*/
+func (e *Encoder) DecodeParity(src string, start, end Point) (msg string, corrupted int, err error) {
+
+	img, err := readImage(src)
+	if err != nil {
+		return msg, 0, err
+	}
+
+	return e.DecodeImageParity(img, start, end)
+}
+
+// DecodeImageParity is like DecodeParity but reads msg directly
+// from img.
+func (e *Encoder) DecodeImageParity(img image.Image, start, end Point) (msg string, corrupted int, err error) {
+
+	if !e.parity {
+		return msg, 0, errors.New("parity is not enabled")
+	}
+
+	d := *e
+	d.corrupt = make(map[Point]bool)
+	b, err := d.decodeImage(context.Background(), img, start, end)
+	return string(b), len(d.corrupt), err
+}
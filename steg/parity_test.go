@@ -0,0 +1,66 @@
+package steg
+
+import (
+	"testing"
+)
+
+func TestDecodeParity(t *testing.T) {
+
+	for _, opts := range [][]Option{
+		{WithParity(), WithLengthHeader()},
+		{WithParity(), WithScatterSeed(3), WithLengthHeader()},
+		{WithParity(), WithErrorCorrection(ECCHamming74), WithScatterSeed(3), WithLengthHeader()},
+		{WithParity(), WithLengthHeader(), WithChecksum(), WithMagic([]byte("MAGIC"))},
+	} {
+
+		e, err := NewEncoder(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// A short message leaves most of the pixels read along with
+		// the header unwritten.
+		for _, msg := range []string{"hi", "parity checked"} {
+
+			img := noisyNRGBA(64, 64)
+			start := Point{2, 1}
+			end, err := e.EncodeImage(img, msg, start)
+			if err != nil {
+				t.Fatalf("%s: %v", e, err)
+			}
+
+			got, corrupted, err := e.DecodeImageParity(img, start, end)
+			if err != nil {
+				t.Fatalf("%s: %v", e, err)
+			}
+			if got != msg {
+				t.Fatalf("%s: got %q, want %q", e, got, msg)
+			}
+			if corrupted != 0 {
+				t.Fatalf("%s: %q: %d pixels fail parity in a clean image", e, msg, corrupted)
+			}
+
+			// Flipping the parity bit of the first pixel leaves the
+			// message intact but must be reported.
+			img.Pix[img.PixOffset(start.X, start.Y)+int(ChannelBlue)] ^= 1
+
+			got, corrupted, err = e.DecodeImageParity(img, start, end)
+			if err != nil {
+				t.Fatalf("%s: %v", e, err)
+			}
+			if got != msg {
+				t.Fatalf("%s: got %q, want %q", e, got, msg)
+			}
+			if corrupted != 1 {
+				t.Fatalf("%s: %q: %d pixels fail parity, want 1", e, msg, corrupted)
+			}
+		}
+	}
+}
+
+func TestDecodeParityNotEnabled(t *testing.T) {
+	var e Encoder
+	if _, _, err := e.DecodeImageParity(noisyNRGBA(8, 8), Point{}, Point{7, 7}); err == nil {
+		t.Fatal("DecodeImageParity succeeded without parity enabled")
+	}
+}
@@ -0,0 +1,136 @@
+package steg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image/draw"
+)
+
+/*
+EncodeAt is like Encode but writes msg to the pixels given, in
+order, rather than to the pixels following a start point, for
+schemes that choose their own pixels such as a custom traversal
+or one driven by a key. Each pixel holds as many bits as it would
+for Encode, so msg needs
+
+	ceil(len(msg)*8 / (len(channels)*depth))
+
+of them; any after that are left untouched. msg is written as it
+is, without any of the framing Encode can add, so a magic marker,
+length header, null terminator and the settings that need the
+whole payload (see DecodeReader) can't be used with EncodeAt.
+DecodeAt must then be told the length of msg to read it back.
+
+EncodeAt returns an error wrapping ErrMsgTooLarge if too few
+pixels are given, and an error if any of them is outside the
+image or given more than once.
+*/
+func (e *Encoder) EncodeAt(src, dst, msg string, pixels []Point) error {
+
+	if len(msg) == 0 {
+		return ErrMsgEmpty
+	}
+	if err := e.checkAt(); err != nil {
+		return err
+	}
+
+	_, err := e.encodeFile(src, dst, func(img draw.Image) (Point, error) {
+
+		c, err := e.carrierFor(img)
+		if err != nil {
+			return Point{}, err
+		}
+		if err = alphaCheck(img, e.activeChannels()); err != nil {
+			return Point{}, err
+		}
+		if err = checkPixels(c, pixels); err != nil {
+			return Point{}, err
+		}
+
+		n := e.pixelsFor(len(msg) * e.byteBits())
+		if n > len(pixels) {
+			return Point{}, fmt.Errorf("%w: %d pixels given, %d needed", ErrMsgTooLarge, len(pixels), n)
+		}
+
+		_, err = e.writeMsg(context.Background(), c, n, func(i int) Point {
+			return pixels[i]
+		}, []byte(msg))
+		return Point{}, err
+	})
+
+	return err
+}
+
+/*
+DecodeAt returns the message of byteLen bytes written by EncodeAt
+to pixels, which must be the same pixels in the same order. It
+returns an error if byteLen is less than 1, if the pixels can't
+hold byteLen bytes or if any of them is outside the image or
+given more than once.
+*/
+func (e *Encoder) DecodeAt(src string, pixels []Point, byteLen int) (string, error) {
+
+	if byteLen < 1 {
+		return "", fmt.Errorf("byteLen out of bounds: got %d, wanted 1 or more", byteLen)
+	}
+	if err := e.checkAt(); err != nil {
+		return "", err
+	}
+	if err := e.checkDecodeLimit(byteLen); err != nil {
+		return "", err
+	}
+
+	img, err := readImage(src)
+	if err != nil {
+		return "", err
+	}
+
+	c, err := e.carrierFor(img)
+	if err != nil {
+		return "", err
+	}
+	if err = checkPixels(c, pixels); err != nil {
+		return "", err
+	}
+
+	n := e.pixelsFor(byteLen * e.byteBits())
+	if n > len(pixels) {
+		return "", fmt.Errorf("%d pixels given, %d needed for %d bytes", len(pixels), n, byteLen)
+	}
+
+	msg, err := e.readMsg(context.Background(), c, n, func(i int) Point {
+		return pixels[i]
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(msg[:byteLen]), nil
+}
+
+// checkAt returns an error if the encoder frames messages in a
+// way EncodeAt and DecodeAt don't support.
+func (e *Encoder) checkAt() error {
+	if len(e.magic) > 0 || e.lengthHeader || e.terminator || e.wholePayload() {
+		return errors.New("EncodeAt and DecodeAt can't be used with a magic marker, length header, null terminator or settings that need the whole payload")
+	}
+	return nil
+}
+
+// checkPixels returns an error if any of pixels is outside c or
+// appears more than once.
+func checkPixels(c carrier, pixels []Point) error {
+	bounds := c.bounds()
+	seen := make(map[Point]int, len(pixels))
+	for i, p := range pixels {
+		if !inBounds(bounds, p) {
+			return fmt.Errorf("pixel %d at (%d, %d) is outside the image", i, p.X, p.Y)
+		}
+		if j, ok := seen[p]; ok {
+			return fmt.Errorf("pixel %d at (%d, %d) repeats pixel %d", i, p.X, p.Y, j)
+		}
+		seen[p] = i
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package steg
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"math/rand"
+	"testing"
+)
+
+func TestEncodeAt(t *testing.T) {
+
+	const msg = "at pixels of my choosing"
+
+	// Every pixel of the image in a shuffled order, of which
+	// only those the message needs are changed.
+	var pixels []Point
+	for _, i := range rand.New(rand.NewSource(6)).Perm(32 * 32) {
+		pixels = append(pixels, Point{i % 32, i / 32})
+	}
+
+	for _, opts := range [][]Option{
+		nil,
+		{WithChannels(ChannelRed, ChannelGreen, ChannelBlue), WithBitDepth(2)},
+	} {
+
+		e, err := NewEncoder(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		orig := noisyNRGBA(32, 32)
+		dst := dstPath(t, ".png")
+		if err = e.EncodeAt(writePNG(t, orig), dst, msg, pixels); err != nil {
+			t.Fatalf("%s: %v", e, err)
+		}
+
+		got, err := e.DecodeAt(dst, pixels, len(msg))
+		if err != nil {
+			t.Fatalf("%s: %v", e, err)
+		}
+		if got != msg {
+			t.Fatalf("%s: got %q, want %q", e, got, msg)
+		}
+
+		img, err := readImage(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved := image.NewNRGBA(img.Bounds())
+		draw.Draw(saved, saved.Rect, img, image.Point{}, draw.Src)
+
+		used := make(map[int]bool)
+		for _, p := range pixels[:e.pixelsFor(len(msg)*8)] {
+			used[p.Y*32+p.X] = true
+		}
+		for _, i := range changedPixels(orig, saved) {
+			if !used[i] {
+				t.Fatalf("%s: pixel %d changed but wasn't needed", e, i)
+			}
+		}
+	}
+}
+
+func TestEncodeAtErrors(t *testing.T) {
+
+	e, err := NewEncoder()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := writePNG(t, noisyNRGBA(8, 8))
+	pixels := []Point{{0, 0}, {1, 0}, {2, 0}, {3, 0}, {4, 0}, {5, 0}, {6, 0}, {7, 0}}
+
+	if err = e.EncodeAt(src, dstPath(t, ".png"), "ab", pixels); !errors.Is(err, ErrMsgTooLarge) {
+		t.Errorf("got %v, want ErrMsgTooLarge", err)
+	}
+	if err = e.EncodeAt(src, dstPath(t, ".png"), "a", append(pixels[:7:7], Point{8, 0})); err == nil {
+		t.Error("EncodeAt accepted a pixel outside the image")
+	}
+	if err = e.EncodeAt(src, dstPath(t, ".png"), "a", append(pixels[:7:7], Point{0, 0})); err == nil {
+		t.Error("EncodeAt accepted a repeated pixel")
+	}
+	if _, err = e.DecodeAt(src, pixels, 2); err == nil {
+		t.Error("DecodeAt read more bytes than the pixels hold")
+	}
+	if _, err = e.DecodeAt(src, pixels, 0); err == nil {
+		t.Error("DecodeAt accepted a byteLen of 0")
+	}
+
+	for _, opt := range []Option{WithLengthHeader(), WithMagic([]byte("AT")), WithNullTerminator(), WithChecksum()} {
+		e, err := NewEncoder(opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = e.EncodeAt(src, dstPath(t, ".png"), "a", pixels); err == nil {
+			t.Errorf("%s: EncodeAt accepted framing it can't write", e)
+		}
+		if _, err = e.DecodeAt(src, pixels, 1); err == nil {
+			t.Errorf("%s: DecodeAt accepted framing it can't read", e)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package steg
+
+import (
+	"fmt"
+	"image"
+)
+
+/*
+RelativePoint returns the pixel of the image at src that lies
+fx of the way across it and fy of the way down, so that start
+points can be given independently of the size of the image.
+fx and fy must be in the range 0-1 (inclusive), where 1 gives
+the last column or row.
+*/
+func RelativePoint(src string, fx, fy float64) (Point, error) {
+
+	bounds, err := readBounds(src)
+	if err != nil {
+		return Point{}, err
+	}
+
+	return relativePoint(bounds, fx, fy)
+}
+
+func relativePoint(bounds image.Rectangle, fx, fy float64) (Point, error) {
+
+	if !(fx >= 0 && fx <= 1) {
+		return Point{}, fmt.Errorf("fx out of bounds: got %g, wanted 0-1 inclusive", fx)
+	}
+	if !(fy >= 0 && fy <= 1) {
+		return Point{}, fmt.Errorf("fy out of bounds: got %g, wanted 0-1 inclusive", fy)
+	}
+	if bounds.Empty() {
+		return Point{}, ErrStartOutOfBounds
+	}
+
+	return Point{
+		X: bounds.Min.X + fraction(bounds.Dx(), fx),
+		Y: bounds.Min.Y + fraction(bounds.Dy(), fy),
+	}, nil
+}
+
+// fraction returns the index f of the way along n items,
+// keeping f = 1 on the last item.
+func fraction(n int, f float64) int {
+	i := int(f * float64(n))
+	if i >= n {
+		i = n - 1
+	}
+	return i
+}
+
+/*
+PixelIndex returns the index of p among the pixels of bounds in
+the default row-major traversal, where bounds.Min is 0, the
+pixel to its right 1 and the first pixel of the second row
+bounds.Dx(). Offsets between messages can be worked out from the
+indexes of their start and end points. PixelIndex returns -1 if p
+lies outside bounds.
+*/
+func PixelIndex(bounds image.Rectangle, p Point) int {
+	if !inBounds(bounds, p) {
+		return -1
+	}
+	return offsetFromMin(bounds, TraversalRowMajor, p)
+}
+
+/*
+PointAt is the inverse of PixelIndex, returning the pixel of
+bounds with the given row-major index. Indexes that aren't in
+the range 0 to bounds.Dx()*bounds.Dy()-1 give a point outside
+bounds.
+*/
+func PointAt(bounds image.Rectangle, index int) Point {
+	if bounds.Empty() {
+		return Point(bounds.Min)
+	}
+	return pointAt(bounds, TraversalRowMajor, index)
+}
@@ -0,0 +1,81 @@
+package steg
+
+import (
+	"bytes"
+	"image/png"
+	"sync"
+)
+
+/*
+The pools below hold the larger scratch buffers used while
+encoding and decoding so that services handling many images
+reuse them rather than allocating new ones for every call. An
+Encoder may be used by several goroutines at once so the pools
+are shared by all of them. Buffers grown past a size cap by an
+unusually large image are dropped rather than put back, so that
+one such image doesn't leave the pool holding its memory.
+*/
+
+const (
+	// maxPooledBuffer is the largest capacity in bytes of a
+	// buffer put back in imageBuffers.
+	maxPooledBuffer = 32 << 20
+
+	// maxPooledInt64s is the largest capacity of a slice put
+	// back in int64Tables.
+	maxPooledInt64s = maxPooledBuffer / 8
+)
+
+// pngBuffers implements png.EncoderBufferPool.
+type pngBuffers struct {
+	pool sync.Pool
+}
+
+func (p *pngBuffers) Get() *png.EncoderBuffer {
+	b, _ := p.pool.Get().(*png.EncoderBuffer)
+	return b
+}
+
+func (p *pngBuffers) Put(b *png.EncoderBuffer) {
+	p.pool.Put(b)
+}
+
+var pngPool = &pngBuffers{}
+
+// imageBuffers holds the buffers encoded images are written to
+// before being saved.
+var imageBuffers = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+func getBuffer() *bytes.Buffer {
+	return imageBuffers.Get().(*bytes.Buffer)
+}
+
+func putBuffer(b *bytes.Buffer) {
+	if b.Cap() > maxPooledBuffer {
+		return
+	}
+	b.Reset()
+	imageBuffers.Put(b)
+}
+
+// int64Tables holds the summed-area tables used by denseOffsets.
+var int64Tables sync.Pool
+
+// getInt64s returns a zeroed slice of n int64s.
+func getInt64s(n int) []int64 {
+	if p, ok := int64Tables.Get().(*[]int64); ok && cap(*p) >= n {
+		s := (*p)[:n]
+		clear(s)
+		return s
+	}
+	return make([]int64, n)
+}
+
+func putInt64s(s []int64) {
+	if cap(s) > maxPooledInt64s {
+		return
+	}
+	int64Tables.Put(&s)
+}
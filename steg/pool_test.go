@@ -0,0 +1,65 @@
+package steg
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestPutBufferDropsLargeBuffers(t *testing.T) {
+
+	small := new(bytes.Buffer)
+	small.Grow(1024)
+	putBuffer(small)
+	if b := getBuffer(); b.Len() != 0 {
+		t.Fatalf("pooled buffer holds %d bytes, want 0", b.Len())
+	}
+
+	large := new(bytes.Buffer)
+	large.Grow(maxPooledBuffer + 1)
+	putBuffer(large)
+	if getBuffer() == large {
+		t.Fatalf("buffer of %d bytes was pooled, limit is %d", large.Cap(), maxPooledBuffer)
+	}
+}
+
+// emptyPools discards everything held by the pools so that the
+// next encode allocates its buffers afresh.
+func emptyPools() {
+	pngPool.pool = sync.Pool{}
+	imageBuffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+	int64Tables = sync.Pool{}
+}
+
+// Run with -benchmem to compare allocations with the pools in use
+// and with them emptied before every encode.
+func BenchmarkEncodePooled(b *testing.B) {
+
+	e, err := NewEncoder(WithDensity(1, 3))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	src := writePNG(b, noisyNRGBA(512, 512))
+	dst := dstPath(b, ".png")
+
+	for _, pooled := range []bool{true, false} {
+
+		name := "pooled"
+		if !pooled {
+			name = "unpooled"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if !pooled {
+					emptyPools()
+				}
+				if _, err := e.Encode(src, dst, "benchmark", Point{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
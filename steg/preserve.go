@@ -0,0 +1,88 @@
+package steg
+
+import (
+	"bytes"
+	"image"
+	"io"
+)
+
+// PNG colour types, from the IHDR chunk.
+const (
+	pngRGB       = 2
+	pngGrayAlpha = 4
+	pngRGBA      = 6
+)
+
+/*
+SetPreserveFormat specifies whether a PNG dst is written with
+the colour type of a PNG src as far as png.Encode allows, so
+that re-encoding changes the structure of the file as little as
+possible. Greyscale, 16-bit and paletted images are already
+written as they were read, but png.Encode drops the alpha
+channel of any image that happens to be fully opaque, so an RGBA
+src with no transparent pixels would otherwise be saved as RGB.
+With SetPreserveFormat enabled such images keep their alpha
+channel.
+
+Some parts of the format can't be kept because png.Encode can't
+write them: greyscale images with an alpha channel are saved as
+RGBA, greyscale images of fewer than 8 bits as 8-bit greyscale
+and interlaced images without interlacing, while the bit depth
+of paletted images depends on the size of their palette. Writing
+to the alpha channel of a src without one gives dst an alpha
+channel. It is disabled by default.
+*/
+func (e *Encoder) SetPreserveFormat(enabled bool) {
+	e.preserveFormat = enabled
+}
+
+// pngColorType returns the colour type of the PNG in data, or
+// false if data doesn't begin with a PNG signature and IHDR.
+func pngColorType(data []byte) (byte, bool) {
+	chunks := splitPNGChunks(data)
+	if len(chunks) == 0 || string(chunks[0][4:8]) != "IHDR" || len(chunks[0]) < 8+13+4 {
+		return 0, false
+	}
+	return chunks[0][8+9], true
+}
+
+// translucent makes png.Encode write the alpha channel of an
+// image whose pixels are all opaque.
+type translucent struct {
+	image.Image
+}
+
+func (translucent) Opaque() bool {
+	return false
+}
+
+/*
+preserveFormat wraps enc so that its output keeps the alpha
+channel of the PNG in src, re-encoding img if it was dropped.
+Output that isn't a PNG is written unchanged.
+*/
+func preserveFormat(enc imageEncoder, src []byte) imageEncoder {
+
+	ct, ok := pngColorType(src)
+	if !ok || (ct != pngRGBA && ct != pngGrayAlpha) {
+		return enc
+	}
+
+	return func(w io.Writer, img image.Image) error {
+
+		var buf bytes.Buffer
+		if err := enc(&buf, img); err != nil {
+			return err
+		}
+
+		if out, ok := pngColorType(buf.Bytes()); ok && out == pngRGB {
+			buf.Reset()
+			if err := enc(&buf, translucent{img}); err != nil {
+				return err
+			}
+		}
+
+		_, err := buf.WriteTo(w)
+		return err
+	}
+}
@@ -0,0 +1,47 @@
+package steg
+
+/*
+SetProgress specifies a function that is called periodically
+while a message is written or read, roughly every 1% of the
+way through. done is the number of message bits processed so
+far and total is the number of bits being processed, which for
+Encode is len(msg)*8 plus any bits added by the encoder's
+settings (headers, checksums and so on). The final call always
+has done equal to total. Decoding with the length header reads
+the header first and so makes two runs of calls, while
+DecodeUntilNull makes a run for each block of pixels it reads
+until the terminator is found. fn is called concurrently if the
+encoder is used by several goroutines at once. Passing nil
+disables reporting, which is the default.
+*/
+func (e *Encoder) SetProgress(fn func(done, total int)) {
+	e.progress = fn
+}
+
+type reporter struct {
+	fn    func(done, total int)
+	total int
+	next  int
+}
+
+func (e *Encoder) reporter(total int) reporter {
+	return reporter{fn: e.progress, total: total}
+}
+
+func (r *reporter) report(done int) {
+	if r.fn == nil || done < r.next {
+		return
+	}
+	r.fn(done, r.total)
+	step := r.total / 100
+	if step < 1 {
+		step = 1
+	}
+	r.next = done + step
+}
+
+func (r *reporter) finish() {
+	if r.fn != nil && r.next <= r.total {
+		r.fn(r.total, r.total)
+	}
+}
@@ -0,0 +1,120 @@
+package steg
+
+import (
+	"context"
+	"errors"
+)
+
+/*
+DecodeRange decodes the part of the message written from start
+that lies between from and to, so that a large message can be
+decoded in chunks, possibly in parallel, and reassembled.
+byteOffset is the position in the message of the first byte
+returned. The bytes returned are those whose first bit lies in
+the pixels from from up to but not including to; a byte that
+runs on past to is read in full. Decoding consecutive ranges,
+each from the previous to, therefore returns every byte of the
+message exactly once.
+
+The magic marker and length header, when enabled, are read from
+start and skipped, so byteOffset counts from the start of the
+message itself; with the length header no bytes are returned
+past the end of the message. With a null terminator the
+terminator and anything after it are returned as they are read.
+Settings that need the whole payload before the message can be
+decoded, such as compression, encryption, checksums, scatter and
+the others listed by DecodeReader, can't be used with
+DecodeRange.
+
+DecodeRange returns an error if from or to is outside the image,
+if from precedes start or if to does not come after from.
+*/
+func (e *Encoder) DecodeRange(src string, start, from, to Point) (bytes []byte, byteOffset int, err error) {
+
+	if e.wholePayload() {
+		return nil, 0, errors.New("DecodeRange can't be used with settings that need the whole payload; see DecodeReader")
+	}
+
+	img, err := readImage(src)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	c, err := e.carrierFor(img)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return e.decodeRange(context.Background(), c, start, from, to)
+}
+
+func (e *Encoder) decodeRange(ctx context.Context, c carrier, start, from, to Point) (bytes []byte, byteOffset int, err error) {
+
+	bounds := c.bounds()
+	if !inBounds(bounds, start) || !inBounds(bounds, from) {
+		return nil, 0, ErrStartOutOfBounds
+	}
+	if !inBounds(bounds, to) {
+		return nil, 0, ErrEndOutOfBounds
+	}
+
+	first := offsetFromMin(bounds, e.traversal, start)
+	f := offsetFromMin(bounds, e.traversal, from) - first
+	t := offsetFromMin(bounds, e.traversal, to) - first
+	if f < 0 {
+		return nil, 0, errors.New("from point precedes start point")
+	}
+	if t <= f {
+		return nil, 0, ErrStartAfterEnd
+	}
+
+	n, hdr, err := e.readHeader(ctx, c, start)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	bpp := e.bitsPerPixel()
+	cb := e.byteBits()
+	hdrBits := hdr * cb
+
+	// Bytes whose first bit lies in the range, counted from the
+	// start of the message.
+	lo := max(0, ceilDiv(f*bpp-hdrBits, cb))
+	hi := ceilDiv(t*bpp-hdrBits, cb)
+	if e.lengthHeader {
+		hi = min(hi, n)
+	}
+
+	// The last byte must be read in full, without running past
+	// the last pixel of the image.
+	avail := bounds.Dx()*bounds.Dy() - first
+	hi = min(hi, (avail*bpp-hdrBits)/cb)
+	if hi <= lo {
+		return nil, lo, nil
+	}
+	if err = e.checkDecodeLimit(hi - lo); err != nil {
+		return nil, 0, err
+	}
+
+	bit := hdrBits + lo*cb
+	p := bit / bpp
+	pixels := ceilDiv(hdrBits+hi*cb, bpp) - p
+
+	bytes, err = e.readMsgAt(ctx, c, pixels, func(i int) Point {
+		return pointAt(bounds, e.traversal, first+p+i)
+	}, hdr+lo, bit%bpp)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return bytes[:hi-lo], lo, nil
+}
+
+// ceilDiv returns n/d rounded towards positive infinity, for
+// positive d.
+func ceilDiv(n, d int) int {
+	if n <= 0 {
+		return -(-n / d)
+	}
+	return (n + d - 1) / d
+}
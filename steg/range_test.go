@@ -0,0 +1,97 @@
+package steg
+
+import (
+	"errors"
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestDecodeRange(t *testing.T) {
+
+	msg := strings.Repeat("decoded in chunks, ", 20)
+	start := Point{5, 3}
+
+	for _, opts := range [][]Option{
+		nil,
+		{WithMagic([]byte("RNG")), WithLengthHeader()},
+
+		// Bytes cross from one pixel to the next.
+		{WithLengthHeader(), WithChannels(ChannelRed, ChannelGreen, ChannelBlue)},
+		{WithLengthHeader(), WithChannels(ChannelRed, ChannelBlue), WithBitDepth(3), WithTraversal(TraversalColumnMajor)},
+	} {
+
+		e, err := NewEncoder(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dst := dstPath(t, ".png")
+		end, err := e.Encode(writePNG(t, noisyNRGBA(64, 64)), dst, msg, start)
+		if err != nil {
+			t.Fatalf("%s: %v", e, err)
+		}
+
+		bounds := image.Rect(0, 0, 64, 64)
+		first := offsetFromMin(bounds, e.traversal, start)
+		last := offsetFromMin(bounds, e.traversal, end)
+
+		for _, chunk := range []int{7, 100, 333} {
+
+			got := make([]byte, 0, len(msg))
+			for f := first; f < last; f += chunk {
+
+				from := pointAt(bounds, e.traversal, f)
+				to := pointAt(bounds, e.traversal, min(f+chunk, last))
+
+				b, offset, err := e.DecodeRange(dst, start, from, to)
+				if err != nil {
+					t.Fatalf("%s: chunk %d from %v: %v", e, chunk, from, err)
+				}
+				if len(b) > 0 && offset != len(got) {
+					t.Fatalf("%s: chunk %d from %v: offset %d, want %d", e, chunk, from, offset, len(got))
+				}
+				got = append(got, b...)
+			}
+
+			if string(got) != msg {
+				t.Fatalf("%s: chunks of %d pixels give %q, want %q", e, chunk, got, msg)
+			}
+		}
+	}
+}
+
+func TestDecodeRangeErrors(t *testing.T) {
+
+	e, err := NewEncoder(WithLengthHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := dstPath(t, ".png")
+	if _, err = e.Encode(writePNG(t, noisyNRGBA(16, 16)), dst, "range", Point{0, 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		from, to Point
+		want     error
+	}{
+		{Point{0, 16}, Point{0, 5}, ErrStartOutOfBounds},
+		{Point{0, 3}, Point{16, 5}, ErrEndOutOfBounds},
+		{Point{0, 3}, Point{0, 3}, ErrStartAfterEnd},
+		{Point{0, 1}, Point{0, 5}, nil},
+	} {
+		if _, _, err = e.DecodeRange(dst, Point{0, 2}, tt.from, tt.to); err == nil || tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("from %v to %v: got %v, want %v", tt.from, tt.to, err, tt.want)
+		}
+	}
+
+	e, err = NewEncoder(WithLengthHeader(), WithChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = e.DecodeRange(dst, Point{0, 2}, Point{0, 2}, Point{0, 5}); err == nil {
+		t.Error("DecodeRange accepted a checksum")
+	}
+}
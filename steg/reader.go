@@ -0,0 +1,221 @@
+package steg
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+/*
+DecodeReader returns a reader over the message written to src
+between start and end, as Decode would return it. Where the
+encoder's settings allow it the message is extracted from the
+image a block of pixels at a time as it is read, so it is never
+held in memory as a whole. Compression, encryption, checksums,
+scatter, spread, density, a pixel stride, a separate header
+depth and the format header all need the whole payload before
+any of the message can be returned, so with any of those enabled
+the first call to Read decodes the message in full.
+
+Errors about src or the bounds of start and end are returned by
+DecodeReader itself. Errors found while decoding, such as
+ErrNoMessage, are returned by Read.
+*/
+func (e *Encoder) DecodeReader(src string, start, end Point) (io.ReadCloser, error) {
+
+	img, err := readImage(src)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := e.carrierFor(img)
+	if err != nil {
+		return nil, err
+	}
+
+	pixels, err := e.span(c.bounds(), start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payloadReader{
+		ctx:    context.Background(),
+		e:      e,
+		c:      c,
+		start:  start,
+		end:    end,
+		first:  offsetFromMin(c.bounds(), e.traversal, start),
+		pixels: pixels,
+		body:   -1,
+	}, nil
+}
+
+var errReaderClosed = errors.New("read from closed reader")
+
+// payloadReader is the reader returned by DecodeReader.
+type payloadReader struct {
+	ctx        context.Context
+	e          *Encoder
+	c          carrier
+	start, end Point
+
+	first  int // offset of start
+	pixels int // pixels between start and end
+	done   int // pixels read so far
+	read   int // bytes read from the image so far
+
+	hdr  bool   // whether the header has been read
+	body int    // bytes of body left when known, otherwise -1
+	raw  []byte // bytes read from the image but not yet returned
+	err  error  // returned once raw is empty
+}
+
+func (r *payloadReader) Read(p []byte) (n int, err error) {
+
+	for len(r.raw) == 0 && r.err == nil {
+		r.err = r.fill()
+	}
+
+	if len(r.raw) == 0 {
+		return 0, r.err
+	}
+
+	n = copy(p, r.raw)
+	r.raw = r.raw[n:]
+	return n, nil
+}
+
+func (r *payloadReader) Close() error {
+	r.c = nil
+	r.raw = nil
+	r.err = errReaderClosed
+	return nil
+}
+
+// wholePayload reports whether the encoder's settings need the
+// whole payload to be read before any of the message can be
+// decoded.
+func (e *Encoder) wholePayload() bool {
+	return e.compression || e.passphrase != "" || e.checksum || e.scatter || e.spread || e.densityWindow != 0 || e.headerDepth != 0 || e.formatHeader || e.stride != 0 || e.skipTransparent || e.ecc != ECCNone || e.armor != ArmorNone || e.mirror || e.metadata != nil
+}
+
+/*
+fill reads the next block of pixels into r.raw, dropping any
+header and anything after the end of the message. It returns
+io.EOF once the whole message has been read.
+*/
+func (r *payloadReader) fill() error {
+
+	if r.c == nil {
+		return errReaderClosed
+	}
+
+	if r.e.wholePayload() {
+		if r.hdr {
+			return io.EOF
+		}
+		r.hdr = true
+		msg, err := r.e.decodeCarrier(r.ctx, r.c, r.start, r.end)
+		r.raw = msg
+		return err
+	}
+
+	if r.body == 0 {
+		return io.EOF
+	}
+
+	if r.done == r.pixels {
+		switch {
+		case r.e.terminator:
+			return ErrNoTerminator
+		case !r.hdr && len(r.raw) < len(r.e.magic):
+			return ErrNoMessage
+		case !r.hdr:
+			return errors.New("decoded data is too short to hold a length header")
+		case r.body > 0:
+			return errors.New("length header exceeds decoded data")
+		}
+		return io.EOF
+	}
+
+	bounds := r.c.bounds()
+	t := r.e.traversal
+	offset := r.first + r.done
+
+	pixels := nullBlock
+	if r.pixels-r.done < pixels {
+		pixels = r.pixels - r.done
+	}
+
+	b, err := r.e.readMsgFrom(r.ctx, r.c, pixels, func(i int) Point {
+		return pointAt(bounds, t, offset+i)
+	}, r.read)
+	if err != nil {
+		return err
+	}
+	r.done += pixels
+	r.read += len(b)
+	if err = r.e.checkDecodeLimit(r.read); err != nil {
+		return err
+	}
+	r.raw = append(r.raw, b...)
+
+	if !r.hdr {
+		if err := r.header(); err != nil || !r.hdr {
+			return err
+		}
+	}
+
+	if r.e.terminator {
+		if i := bytes.IndexByte(r.raw, 0); i >= 0 {
+			r.raw = r.raw[:i]
+			r.body = i
+		}
+	}
+
+	if r.body >= 0 && len(r.raw) > r.body {
+		r.raw = r.raw[:r.body]
+	}
+	if r.body > 0 {
+		r.body -= len(r.raw)
+	}
+
+	return nil
+}
+
+// header checks the magic marker and reads the length header
+// from the start of r.raw once enough of it has been read,
+// leaving r.hdr false until then.
+func (r *payloadReader) header() error {
+
+	e := r.e
+
+	if len(r.raw) < len(e.magic) {
+		return nil
+	}
+	if err := e.checkMagic(r.raw); err != nil {
+		return err
+	}
+	data := r.raw[len(e.magic):]
+
+	if e.lengthHeader {
+		n, k := binary.Uvarint(data)
+		if k == 0 {
+			return nil
+		}
+		if k < 0 {
+			return errors.New("invalid length header")
+		}
+		data = data[k:]
+		r.body = int(n)
+		if uint64(r.body) != n || r.body < 0 {
+			return errors.New("length header exceeds decoded data")
+		}
+	}
+
+	r.raw = data
+	r.hdr = true
+	return nil
+}
@@ -0,0 +1,82 @@
+package steg
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecodeReader(t *testing.T) {
+
+	long := strings.Repeat("read a block at a time ", 100)
+
+	for _, tt := range []struct {
+		opts []Option
+		msg  string
+	}{
+		{nil, "plain"},
+		{[]Option{WithMagic([]byte("RDR")), WithLengthHeader()}, long},
+		{[]Option{WithNullTerminator(), WithChannels(ChannelRed, ChannelGreen)}, long},
+
+		// These are decoded in full on the first Read.
+		{[]Option{WithLengthHeader(), WithChecksum()}, long},
+		{[]Option{WithLengthHeader(), WithCompression(), WithScatterSeed(9)}, long},
+	} {
+
+		e, err := NewEncoder(tt.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dst := dstPath(t, ".png")
+		start := Point{2, 1}
+		end, err := e.Encode(writePNG(t, noisyNRGBA(160, 160)), dst, tt.msg, start)
+		if err != nil {
+			t.Fatalf("%s: %v", e, err)
+		}
+
+		r, err := e.DecodeReader(dst, start, end)
+		if err != nil {
+			t.Fatalf("%s: %v", e, err)
+		}
+		if err = iotest.TestReader(r, []byte(tt.msg)); err != nil {
+			t.Fatalf("%s: %v", e, err)
+		}
+		if err = r.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = r.Read(make([]byte, 1)); err == nil || err == io.EOF {
+			t.Fatalf("%s: Read after Close gave %v", e, err)
+		}
+	}
+}
+
+func TestDecodeReaderErrors(t *testing.T) {
+
+	e, err := NewEncoder(WithMagic([]byte("RDR")), WithLengthHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := writePNG(t, noisyNRGBA(16, 16))
+
+	// Bounds are checked up front.
+	if _, err = e.DecodeReader(src, Point{16, 0}, Point{15, 15}); !errors.Is(err, ErrStartOutOfBounds) {
+		t.Fatalf("got %v, want ErrStartOutOfBounds", err)
+	}
+	if _, err = e.DecodeReader(src, Point{0, 0}, Point{0, 16}); !errors.Is(err, ErrEndOutOfBounds) {
+		t.Fatalf("got %v, want ErrEndOutOfBounds", err)
+	}
+
+	// Anything found while decoding is returned by Read.
+	r, err := e.DecodeReader(src, Point{0, 0}, Point{15, 15})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err = io.ReadAll(r); !errors.Is(err, ErrNoMessage) {
+		t.Fatalf("got %v, want ErrNoMessage", err)
+	}
+}
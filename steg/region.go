@@ -0,0 +1,63 @@
+package steg
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+/*
+EncodeRegion is like Encode but confines msg to the pixels of
+src inside region, so that several messages can be written to
+non-overlapping parts of one image. The message starts at
+region.Min and pixels are visited as if region were the whole
+image, wrapping at its edges rather than at the edges of src.
+
+EncodeRegion returns an error if region is not within the
+bounds of src or msg does not fit inside it.
+*/
+func (e *Encoder) EncodeRegion(src, dst, msg string, region image.Rectangle) (end Point, err error) {
+	return e.encodeFile(src, dst, func(img draw.Image) (Point, error) {
+		c, err := e.regionFor(img, region)
+		if err != nil {
+			return Point{}, err
+		}
+		stats, err := e.encodeCarrier(context.Background(), c, []byte(msg), Point(region.Min))
+		return stats.End, err
+	})
+}
+
+/*
+DecodeRegion reads a message written to region of src by
+EncodeRegion, where end is the point EncodeRegion returned.
+*/
+func (e *Encoder) DecodeRegion(src string, region image.Rectangle, end Point) (msg string, err error) {
+
+	img, err := readImage(src)
+	if err != nil {
+		return msg, err
+	}
+
+	c, err := e.regionFor(img, region)
+	if err != nil {
+		return msg, err
+	}
+
+	b, err := e.decodeCarrier(context.Background(), c, Point(region.Min), end)
+	return string(b), err
+}
+
+func (e *Encoder) regionFor(img image.Image, region image.Rectangle) (carrier, error) {
+
+	c, err := e.carrierFor(img)
+	if err != nil {
+		return nil, err
+	}
+
+	if region.Empty() || !region.In(c.bounds()) {
+		return nil, fmt.Errorf("region %v is not within the image bounds %v", region, c.bounds())
+	}
+
+	return regionCarrier{c, region}, nil
+}
@@ -0,0 +1,46 @@
+package steg
+
+import "image"
+
+/*
+RoundTrip encodes msg into a copy of img from start with an
+Encoder configured by opts, then decodes it again and returns
+the message it read back. img itself is left unchanged and
+nothing is read from or written to disk, which makes RoundTrip
+suitable as the body of a fuzz test checking that what comes out
+is what went in:
+
+	func FuzzRoundTrip(f *testing.F) {
+		img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+		f.Add("hello", 0)
+		f.Fuzz(func(t *testing.T, msg string, bit int) {
+			got, err := steg.RoundTrip(img, msg, steg.Point{}, steg.WithBit(bit))
+			if err == nil && got != msg {
+				t.Fatalf("got %q, want %q", got, msg)
+			}
+		})
+	}
+
+Any error from configuring the encoder, encoding or decoding is
+returned as is.
+*/
+func RoundTrip(img *image.RGBA, msg string, start Point, opts ...Option) (string, error) {
+
+	e, err := NewEncoder(opts...)
+	if err != nil {
+		return "", err
+	}
+
+	cp := &image.RGBA{
+		Pix:    append([]uint8(nil), img.Pix...),
+		Stride: img.Stride,
+		Rect:   img.Rect,
+	}
+
+	end, err := e.EncodeImage(cp, msg, start)
+	if err != nil {
+		return "", err
+	}
+
+	return e.DecodeImage(cp, start, end)
+}
@@ -0,0 +1,130 @@
+package steg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"sort"
+)
+
+// maxSearchMargin is the largest margin DecodeSearch accepts.
+const maxSearchMargin = 8
+
+/*
+DecodeSearch is like Decode but recovers messages from images
+that have since gained a margin of up to k pixels on any of
+their sides, as some tools do when they add a border while
+resaving an image. Every combination of left, top, right and
+bottom margins from 0 to k is tried, fewest pixels of margin
+first, treating the rest of src as the original image; start
+and end are the points the message was encoded with and are
+moved by the left and top margins. The first combination at
+which the magic marker is found and the message decodes is
+used, and the start it was found at is returned with the
+message.
+
+The encoder must have a magic marker (see SetMagic) so that the
+message can be recognised. Margins on the right change where
+rows wrap without moving the marker, so without the checksum
+(see SetChecksum) a message longer than a row may be decoded at
+the wrong margins. When the length header is enabled end is
+ignored. DecodeSearch returns an error if k is outside the
+range of 0-8 (inclusive) and ErrNoMessage if the message is not
+found at any of the margins tried.
+*/
+func (e *Encoder) DecodeSearch(src string, start, end Point, k int) (msg string, found Point, err error) {
+
+	if k < 0 || k > maxSearchMargin {
+		return msg, found, fmt.Errorf("search margin out of bounds: got %d, wanted 0-%d inclusive", k, maxSearchMargin)
+	}
+
+	if len(e.magic) == 0 {
+		return msg, found, errors.New("DecodeSearch requires a magic marker; see SetMagic")
+	}
+
+	img, err := readImage(src)
+	if err != nil {
+		return msg, found, err
+	}
+
+	c, err := e.carrierFor(img)
+	if err != nil {
+		return msg, found, err
+	}
+
+	ctx := context.Background()
+	var decodeErr error
+
+	for _, m := range searchMargins(k) {
+
+		r := image.Rect(
+			c.bounds().Min.X+m.left, c.bounds().Min.Y+m.top,
+			c.bounds().Max.X-m.right, c.bounds().Max.Y-m.bottom,
+		)
+		if r.Empty() {
+			continue
+		}
+
+		d := image.Pt(m.left, m.top)
+		s := Point(image.Point(start).Add(d))
+		if !inBounds(r, s) {
+			continue
+		}
+
+		rc := regionCarrier{c, r}
+		if _, _, err := e.readHeader(ctx, rc, s); err != nil {
+			continue
+		}
+
+		var b []byte
+		if e.lengthHeader {
+			b, err = e.decodeAuto(ctx, rc, s)
+		} else {
+			b, err = e.decodeCarrier(ctx, rc, s, Point(image.Point(end).Add(d)))
+		}
+		if err != nil {
+			if decodeErr == nil {
+				decodeErr = err
+			}
+			continue
+		}
+
+		return string(b), s, nil
+	}
+
+	if decodeErr != nil {
+		return msg, found, decodeErr
+	}
+
+	return msg, found, ErrNoMessage
+}
+
+type margins struct {
+	left, top, right, bottom int
+}
+
+// searchMargins returns every combination of margins from 0 to
+// k, ordered by their total.
+func searchMargins(k int) []margins {
+
+	var ms []margins
+	for l := 0; l <= k; l++ {
+		for t := 0; t <= k; t++ {
+			for r := 0; r <= k; r++ {
+				for b := 0; b <= k; b++ {
+					ms = append(ms, margins{l, t, r, b})
+				}
+			}
+		}
+	}
+
+	total := func(m margins) int {
+		return m.left + m.top + m.right + m.bottom
+	}
+	sort.SliceStable(ms, func(i, j int) bool {
+		return total(ms[i]) < total(ms[j])
+	})
+
+	return ms
+}
@@ -0,0 +1,99 @@
+package steg
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"testing"
+)
+
+// withBorder returns img with a border of the given widths added
+// on its left, top, right and bottom.
+func withBorder(img *image.NRGBA, l, t, r, b int) *image.NRGBA {
+	bounds := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, bounds.Dx()+l+r, bounds.Dy()+t+b))
+	draw.Draw(out, bounds.Add(image.Pt(l, t)), img, bounds.Min, draw.Src)
+	return out
+}
+
+func TestDecodeSearch(t *testing.T) {
+
+	const msg = "moved by a border"
+	start := Point{3, 2}
+
+	for _, tt := range []struct {
+		opts    []Option
+		margins []margins
+	}{
+		{
+			[]Option{WithMagic([]byte("SRCH")), WithLengthHeader(), WithChecksum()},
+			[]margins{{0, 0, 0, 0}, {1, 0, 0, 0}, {0, 2, 1, 0}, {2, 1, 2, 2}},
+		},
+
+		// Without the checksum a right margin can't be told apart
+		// from none, as it doesn't move the marker.
+		{
+			[]Option{WithMagic([]byte("SRCH"))},
+			[]margins{{0, 0, 0, 0}, {1, 0, 0, 0}, {0, 2, 0, 1}, {2, 1, 0, 2}},
+		},
+	} {
+
+		e, err := NewEncoder(tt.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		img := noisyNRGBA(32, 32)
+		end, err := e.EncodeImage(img, msg, start)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, m := range tt.margins {
+
+			src := writePNG(t, withBorder(img, m.left, m.top, m.right, m.bottom))
+			got, found, err := e.DecodeSearch(src, start, end, 2)
+			if err != nil {
+				t.Fatalf("%s: margins %v: %v", e, m, err)
+			}
+			if got != msg {
+				t.Fatalf("%s: margins %v: got %q, want %q", e, m, got, msg)
+			}
+			if want := (Point{start.X + m.left, start.Y + m.top}); found != want {
+				t.Fatalf("%s: margins %v: found at %v, want %v", e, m, found, want)
+			}
+		}
+
+		// A margin wider than k isn't searched.
+		src := writePNG(t, withBorder(img, 3, 0, 0, 0))
+		if _, _, err = e.DecodeSearch(src, start, end, 2); err == nil {
+			t.Fatalf("%s: found a message past the margins searched", e)
+		}
+	}
+}
+
+func TestDecodeSearchErrors(t *testing.T) {
+
+	src := writePNG(t, noisyNRGBA(16, 16))
+
+	e, err := NewEncoder(WithMagic([]byte("SRCH")), WithLengthHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []int{-1, maxSearchMargin + 1} {
+		if _, _, err = e.DecodeSearch(src, Point{}, Point{15, 15}, k); err == nil {
+			t.Errorf("DecodeSearch accepted a margin of %d", k)
+		}
+	}
+	if _, _, err = e.DecodeSearch(src, Point{}, Point{15, 15}, 1); !errors.Is(err, ErrNoMessage) {
+		t.Errorf("got %v from an image without a message, want ErrNoMessage", err)
+	}
+
+	e, err = NewEncoder(WithLengthHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = e.DecodeSearch(src, Point{}, Point{15, 15}, 1); err == nil {
+		t.Error("DecodeSearch succeeded without a magic marker")
+	}
+}
@@ -0,0 +1,122 @@
+package steg
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (c Channel) String() string {
+	switch c {
+	case ChannelRed:
+		return "red"
+	case ChannelGreen:
+		return "green"
+	case ChannelBlue:
+		return "blue"
+	case ChannelAlpha:
+		return "alpha"
+	case chanCb:
+		return "Cb"
+	case chanCr:
+		return "Cr"
+	}
+	return fmt.Sprintf("Channel(%d)", int(c))
+}
+
+func (t Traversal) String() string {
+	switch t {
+	case TraversalRowMajor:
+		return "row-major"
+	case TraversalColumnMajor:
+		return "column-major"
+	case TraversalHilbert:
+		return "hilbert"
+	}
+	return fmt.Sprintf("Traversal(%d)", int(t))
+}
+
+/*
+String describes the encoder's settings on one line, for logging
+and for working out why a message encoded with one encoder won't
+decode with another. The msg bit of each channel, the bit depth,
+the traversal and the framing settings are always included, while
+scatter, spread and other optional settings are only listed when
+enabled. The passphrase and bit hopping key are not included,
+only whether they are set.
+*/
+func (e *Encoder) String() string {
+
+	var b strings.Builder
+
+	b.WriteString("channels=")
+	for i, c := range e.activeChannels() {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s:%d", c, e.msgBit(c))
+	}
+
+	fmt.Fprintf(&b, " depth=%d traversal=%s", e.bitDepth(), e.traversal)
+	fmt.Fprintf(&b, " magic=%q lengthHeader=%t checksum=%t compression=%t passphrase=%t",
+		e.magic, e.lengthHeader, e.checksum, e.compression, e.passphrase != "")
+
+	if e.bitOrder != MSBFirst {
+		fmt.Fprintf(&b, " bitOrder=%s", e.bitOrder)
+	}
+	if e.formatHeader {
+		b.WriteString(" formatHeader=true")
+	}
+	if e.headerDepth != 0 {
+		fmt.Fprintf(&b, " headerDepth=%d", e.headerDepth)
+	}
+	if e.terminator {
+		b.WriteString(" nullTerminator=true")
+	}
+	if e.scatter {
+		fmt.Fprintf(&b, " scatterSeed=%d", e.scatterSeed)
+	}
+	if e.spread {
+		b.WriteString(" spread=true")
+	}
+	if e.hopKey != "" {
+		fmt.Fprintf(&b, " bitHopping=0-%d", e.hopMax)
+	}
+	if e.stride != 0 {
+		fmt.Fprintf(&b, " pixelStride=%d/%d", e.stride, e.phase)
+	}
+	if e.skipTransparent {
+		b.WriteString(" skipTransparent=true")
+	}
+	if e.metadata != nil {
+		b.WriteString(" metadata=true")
+	}
+	if e.mirror {
+		b.WriteString(" mirror=true")
+	}
+	if e.armor != ArmorNone {
+		fmt.Fprintf(&b, " armor=%s", e.armor)
+	}
+	if e.ecc != ECCNone {
+		fmt.Fprintf(&b, " errorCorrection=%s", e.ecc)
+	}
+	if e.parity {
+		b.WriteString(" parity=true")
+	}
+	if e.sidecar {
+		b.WriteString(" sidecar=true")
+	}
+	if e.verifyDisk {
+		b.WriteString(" verifyOnDisk=true")
+	}
+	if e.parallelism > 1 {
+		fmt.Fprintf(&b, " parallelism=%d", e.parallelism)
+	}
+	if e.maxDecode != 0 {
+		fmt.Fprintf(&b, " maxDecodeBytes=%d", e.maxDecode)
+	}
+	if e.densityWindow != 0 {
+		fmt.Fprintf(&b, " density=%g/%d", e.densityThreshold, e.densityWindow)
+	}
+
+	return b.String()
+}
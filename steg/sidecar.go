@@ -0,0 +1,182 @@
+package steg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// sidecarExt is appended to the path of an image to give the
+// path of its sidecar.
+const sidecarExt = ".steg.json"
+
+/*
+Sidecar is the content of the file written next to an encoded
+image when SetSidecar is enabled. Channels are the channels the
+message was written to, which with SetParity are red and green
+whatever SetChannels was given, and with SetChroma the Cb and Cr
+channels, recorded as 4 and 5. Bits holds the msg bit used for
+each of Channels, Depth is the bit depth and Length the length
+of the message in bytes.
+*/
+type Sidecar struct {
+	Start    Point     `json:"start"`
+	End      Point     `json:"end"`
+	Channels []Channel `json:"channels"`
+	Bits     []int     `json:"bits"`
+	Depth    int       `json:"depth"`
+	Length   int       `json:"length"`
+}
+
+/*
+SetSidecar specifies whether Encode writes a sidecar file
+alongside dst, at dst with ".steg.json" appended, recording the
+start and end points, channels, msg bits, bit depth and length
+of the message as a JSON Sidecar. DecodeSidecar can then decode
+the message without being given any of them, and because the
+sidecar holds the length no length header needs to be written to
+the image. The sidecar is written by Encode, EncodeContext,
+EncodeBytes, EncodeAndVerify, EncodeWithStats, EncodeDetailed,
+EncodeWriter, EncodeFrom and EncodeFile, after dst has been
+saved. It is disabled by default.
+*/
+func (e *Encoder) SetSidecar(enabled bool) {
+	e.sidecar = enabled
+}
+
+// sidecarPath returns the path of the sidecar for the image at
+// path.
+func sidecarPath(path string) string {
+	return path + sidecarExt
+}
+
+// writeSidecar writes the sidecar for a message of msgLen bytes
+// encoded into dst from start to end, if sidecars are enabled.
+func (e *Encoder) writeSidecar(dst string, start, end Point, msgLen int) error {
+
+	if !e.sidecar {
+		return nil
+	}
+
+	channels := e.activeChannels()
+
+	data, err := json.MarshalIndent(Sidecar{
+		Start:    start,
+		End:      end,
+		Channels: channels,
+		Bits:     e.msgBits(channels),
+		Depth:    e.bitDepth(),
+		Length:   msgLen,
+	}, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	dst, err = filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(sidecarPath(dst), append(data, '\n'), 0666)
+}
+
+/*
+DecodeSidecar decodes the message written to the image at src
+using the sidecar written next to it by an encoder with
+SetSidecar enabled. The start and end points, channels, msg bits
+and bit depth are taken from the sidecar and the message is cut
+to the length it records, while all other settings, such as the
+passphrase and magic marker, are those of e. Chroma mode is
+enabled if the sidecar lists the Cb and Cr channels and disabled
+otherwise, and parity is only checked if e has it enabled and the
+sidecar lists the channels parity writes to. An error is
+returned if the sidecar can't be read or holds invalid settings.
+*/
+func (e *Encoder) DecodeSidecar(src string) (string, error) {
+
+	src, err := filepath.Abs(src)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(sidecarPath(src))
+	if err != nil {
+		return "", err
+	}
+
+	var s Sidecar
+	if err = json.Unmarshal(data, &s); err != nil {
+		return "", fmt.Errorf("invalid sidecar: %w", err)
+	}
+
+	d := e.Clone()
+	if err = d.applySidecar(s); err != nil {
+		return "", fmt.Errorf("invalid sidecar: %w", err)
+	}
+
+	msg, err := d.Decode(src, s.Start, s.End)
+	if err != nil {
+		return "", err
+	}
+	if len(msg) > s.Length {
+		msg = msg[:s.Length]
+	}
+
+	return msg, nil
+}
+
+// applySidecar configures e with the settings held by s.
+func (e *Encoder) applySidecar(s Sidecar) error {
+
+	if len(s.Bits) != len(s.Channels) {
+		return errors.New("channels and bits differ in length")
+	}
+	if s.Length < 0 {
+		return fmt.Errorf("length out of bounds: got %d, wanted 0 or more", s.Length)
+	}
+
+	e.chroma = sameChannels(s.Channels, chromaChannels)
+	e.parity = e.parity && sameChannels(s.Channels, parityChannels)
+	if e.chroma {
+		e.channels = nil
+		e.channelBits = nil
+	} else if err := e.SetChannels(s.Channels); err != nil {
+		return err
+	}
+	// Clear the old msg bit so it can't push the new depth out
+	// of bounds before the sidecar's bits replace it.
+	e.bit = 0
+	if err := e.SetBitDepth(s.Depth); err != nil {
+		return err
+	}
+
+	same := true
+	for _, b := range s.Bits {
+		same = same && b == s.Bits[0]
+	}
+	if same {
+		return e.SetMsgBit(s.Bits[0])
+	}
+
+	bits := make(map[Channel]int, len(s.Channels))
+	for i, c := range s.Channels {
+		bits[c] = s.Bits[i]
+	}
+	return e.SetChannelBits(bits)
+}
+
+// sameChannels reports whether a and b list the same channels in
+// the same order.
+func sameChannels(a, b []Channel) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
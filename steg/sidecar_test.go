@@ -0,0 +1,61 @@
+package steg
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestSidecarRoundTrip(t *testing.T) {
+
+	const msg = "read back from the sidecar"
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		channels []Channel
+	}{
+		{"default", nil, []Channel{ChannelRed}},
+		{"channels", []Option{WithChannels(ChannelBlue, ChannelGreen), WithBitDepth(3)}, []Channel{ChannelBlue, ChannelGreen}},
+		{"channel bits", []Option{WithChannelBits(map[Channel]int{ChannelRed: 0, ChannelGreen: 2, ChannelBlue: 1}), WithBitDepth(2)}, []Channel{ChannelRed, ChannelGreen, ChannelBlue}},
+		{"parity", []Option{WithChannels(ChannelBlue), WithParity()}, parityChannels},
+		{"chroma", []Option{WithChroma()}, chromaChannels},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			e, err := NewEncoder(append(tt.opts, WithSidecar())...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dst := dstPath(t, ".png")
+			if _, err = e.Encode(writePNG(t, noisyNRGBA(48, 48)), dst, msg, Point{5, 3}); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(sidecarPath(dst))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var s Sidecar
+			if err = json.Unmarshal(data, &s); err != nil {
+				t.Fatal(err)
+			}
+			if !sameChannels(s.Channels, tt.channels) {
+				t.Fatalf("sidecar lists channels %v, want %v", s.Channels, tt.channels)
+			}
+
+			// Everything needed to decode comes from the sidecar.
+			var d Encoder
+			got, err := d.DecodeSidecar(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != msg {
+				t.Fatalf("got %q, want %q", got, msg)
+			}
+		})
+	}
+}
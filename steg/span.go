@@ -0,0 +1,217 @@
+package steg
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/draw"
+	"math"
+)
+
+// spanHeaderSize is the size of the header written before each
+// part of a spanned message: its index, the number of parts and
+// the length of the part, as big-endian uint16, uint16 and
+// uint32.
+const spanHeaderSize = 2 + 2 + 4
+
+// SpanResult describes one image written by EncodeSpanned.
+type SpanResult struct {
+	Src   string
+	Dst   string
+	End   Point
+	Bytes int
+}
+
+/*
+EncodeSpanned is like Encode but splits msg across the images
+in srcs when it is too large for one of them. Each image is
+filled, in order, with as much of msg as it can hold from start
+and saved to dst formed from dstPattern, a fmt format with one
+%d verb that is replaced by the index of the image in srcs.
+Images that aren't needed because msg has been written in full
+are not saved. A header giving the part's index, the number of
+parts and its length is written before each part so that
+DecodeSpanned can reassemble msg from the saved images in any
+order.
+
+Each part is written with the length header (see
+SetLengthHeader) whether or not it is enabled on e so that only
+start is needed to decode it. An error is returned before any
+image is saved if the images can't hold msg between them; an
+error encoding a later image leaves earlier ones saved.
+*/
+func (e *Encoder) EncodeSpanned(srcs []string, dstPattern string, msg string, start Point) ([]SpanResult, error) {
+
+	if len(msg) == 0 {
+		return nil, ErrMsgEmpty
+	}
+	if fmt.Sprintf(dstPattern, 0) == fmt.Sprintf(dstPattern, 1) {
+		return nil, fmt.Errorf("dstPattern %q does not give each image a different name", dstPattern)
+	}
+
+	s := e.spanner()
+
+	// Work out how the message is split before writing anything
+	// so that a lack of capacity is reported up front.
+	var sizes []int
+	for left := len(msg); left > 0; {
+
+		if len(sizes) == len(srcs) || len(sizes) == math.MaxUint16 {
+			return nil, fmt.Errorf("images can't hold msg: %d bytes are left over", left)
+		}
+
+		ci, err := s.CapacityInfo(srcs[len(sizes)], start)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", srcs[len(sizes)], err)
+		}
+
+		n := ci.MsgBytes() - spanHeaderSize
+		if n > left {
+			n = left
+		}
+		if n < 0 {
+			n = 0
+		}
+
+		sizes = append(sizes, n)
+		left -= n
+	}
+
+	total := countNonZero(sizes)
+	results := make([]SpanResult, 0, total)
+	part := []byte(msg)
+
+	for i, n := range sizes {
+
+		if n == 0 {
+			continue
+		}
+
+		payload := spanPart(len(results), total, part[:n])
+		part = part[n:]
+
+		dst := fmt.Sprintf(dstPattern, i)
+		end, err := s.encodeFile(srcs[i], dst, func(img draw.Image) (Point, error) {
+			stats, err := s.encodeImage(context.Background(), img, payload, start)
+			return stats.End, err
+		})
+		if err != nil {
+			return results, fmt.Errorf("%s: %w", srcs[i], err)
+		}
+
+		results = append(results, SpanResult{
+			Src:   srcs[i],
+			Dst:   dst,
+			End:   end,
+			Bytes: n,
+		})
+	}
+
+	return results, nil
+}
+
+/*
+DecodeSpanned reassembles a message written by EncodeSpanned
+from the images in stegos, which may be given in any order,
+where start is the point that was passed to EncodeSpanned. An
+error is returned if any part is missing or repeated.
+*/
+func (e *Encoder) DecodeSpanned(stegos []string, start Point) (msg string, err error) {
+
+	s := e.spanner()
+
+	var parts [][]byte
+
+	for _, src := range stegos {
+
+		img, err := readImage(src)
+		if err != nil {
+			return msg, err
+		}
+
+		data, err := s.decodeAutoImage(img, start)
+		if err != nil {
+			return msg, fmt.Errorf("%s: %w", src, err)
+		}
+		i, total, data, err := readSpanPart(data)
+		if err != nil {
+			return msg, fmt.Errorf("%s: %w", src, err)
+		}
+
+		if parts == nil {
+			parts = make([][]byte, total)
+		}
+
+		switch {
+		case total != len(parts):
+			return msg, fmt.Errorf("%s: part of a spanned message with %d parts, wanted %d", src, total, len(parts))
+		case i >= total:
+			return msg, fmt.Errorf("%s: part %d out of bounds: wanted 0-%d inclusive", src, i, total-1)
+		case parts[i] != nil:
+			return msg, fmt.Errorf("%s: part %d is repeated", src, i)
+		}
+
+		parts[i] = data
+	}
+
+	if len(parts) == 0 {
+		return msg, errors.New("no images given")
+	}
+
+	var b []byte
+	for i, p := range parts {
+		if p == nil {
+			return msg, fmt.Errorf("part %d of %d is missing", i, len(parts))
+		}
+		b = append(b, p...)
+	}
+
+	return string(b), nil
+}
+
+// spanPart returns part preceded by its header as the i-th of
+// total parts.
+func spanPart(i, total int, part []byte) []byte {
+	var hdr [spanHeaderSize]byte
+	binary.BigEndian.PutUint16(hdr[0:], uint16(i))
+	binary.BigEndian.PutUint16(hdr[2:], uint16(total))
+	binary.BigEndian.PutUint32(hdr[4:], uint32(len(part)))
+	return append(hdr[:], part...)
+}
+
+// readSpanPart splits data written by spanPart into the part's
+// index, the number of parts and the part itself.
+func readSpanPart(data []byte) (i, total int, part []byte, err error) {
+
+	if len(data) < spanHeaderSize {
+		return 0, 0, nil, errors.New("message is too short to be part of a spanned message")
+	}
+
+	i = int(binary.BigEndian.Uint16(data[0:]))
+	total = int(binary.BigEndian.Uint16(data[2:]))
+	n := int(binary.BigEndian.Uint32(data[4:]))
+	part = data[spanHeaderSize:]
+
+	if n != len(part) {
+		return 0, 0, nil, fmt.Errorf("part holds %d bytes, wanted %d", len(part), n)
+	}
+
+	return i, total, part, nil
+}
+
+// spanner returns a copy of e that writes the length header.
+func (e *Encoder) spanner() *Encoder {
+	s := e.Clone()
+	s.lengthHeader = true
+	return s
+}
+
+func countNonZero(ns []int) (count int) {
+	for _, n := range ns {
+		if n != 0 {
+			count++
+		}
+	}
+	return count
+}
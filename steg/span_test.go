@@ -0,0 +1,94 @@
+package steg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncodeSpanned(t *testing.T) {
+
+	e, err := NewEncoder(WithChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srcs := []string{
+		writePNG(t, noisyNRGBA(20, 20)),
+		writePNG(t, noisyNRGBA(20, 20)),
+		writePNG(t, noisyNRGBA(20, 20)),
+		writePNG(t, noisyNRGBA(20, 20)),
+	}
+	pattern := filepath.Join(t.TempDir(), "part%d.png")
+
+	// Too long for one image but short enough to leave the last
+	// one unused.
+	msg := strings.Repeat("spanned ", 11)
+	results, err := e.EncodeSpanned(srcs, pattern, msg, Point{1, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("wrote %d images, want 3", len(results))
+	}
+	if _, err = os.Stat(fmt.Sprintf(pattern, 3)); !os.IsNotExist(err) {
+		t.Fatalf("saved an image that wasn't needed: %v", err)
+	}
+
+	var stegos []string
+	n := 0
+	for i, r := range results {
+		if r.Src != srcs[i] || r.Dst != fmt.Sprintf(pattern, i) {
+			t.Fatalf("result %d is %s -> %s", i, r.Src, r.Dst)
+		}
+		n += r.Bytes
+		stegos = append([]string{r.Dst}, stegos...)
+	}
+	if n != len(msg) {
+		t.Fatalf("parts hold %d bytes, want %d", n, len(msg))
+	}
+
+	// The images may be given in any order.
+	got, err := e.DecodeSpanned(stegos, Point{1, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != msg {
+		t.Fatalf("got %q, want %q", got, msg)
+	}
+
+	if _, err = e.DecodeSpanned(stegos[1:], Point{1, 0}); err == nil {
+		t.Fatal("decoded with a part missing")
+	}
+	if _, err = e.DecodeSpanned(append(stegos, stegos[0]), Point{1, 0}); err == nil {
+		t.Fatal("decoded with a part repeated")
+	}
+}
+
+func TestEncodeSpannedErrors(t *testing.T) {
+
+	e, err := NewEncoder()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srcs := []string{writePNG(t, noisyNRGBA(8, 8)), writePNG(t, noisyNRGBA(8, 8))}
+	dir := t.TempDir()
+
+	if _, err = e.EncodeSpanned(srcs, filepath.Join(dir, "part%d.png"), strings.Repeat("x", 100), Point{}); err == nil {
+		t.Fatal("EncodeSpanned accepted a msg too large for its images")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("saved %d images despite the error", len(entries))
+	}
+
+	if _, err = e.EncodeSpanned(srcs, filepath.Join(dir, "part.png"), "hi", Point{}); err == nil {
+		t.Fatal("EncodeSpanned accepted a pattern that gives every image the same name")
+	}
+
+	if _, err = e.EncodeSpanned(srcs, filepath.Join(dir, "part%d.png"), "", Point{}); err == nil {
+		t.Fatal("EncodeSpanned accepted an empty msg")
+	}
+}
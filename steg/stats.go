@@ -0,0 +1,136 @@
+package steg
+
+import (
+	"context"
+	"image/draw"
+)
+
+/*
+EncodeStats describes the changes made to an image by
+EncodeWithStats. PixelsWritten is the number of pixels message
+bits were written to and PixelsChanged the number of those whose
+value actually changed, since a write leaves a sample as it was
+when its bits already match the message. BitsFlipped is the
+total number of sample bits that changed. MsgLen is the length
+of the message in bytes, which with PointAfter gives End again.
+
+BitsWritten is the number of bits written including any header,
+checksum and other overhead added by the encoder's settings, and
+CapacityBits the number that could have been written from start
+to the end of the image. CapacityUsed is the fraction of the
+capacity taken up, BitsWritten / CapacityBits, which shows how
+much room is left for a longer message or more overhead.
+
+BytesRemaining is the capacity from End, as Capacity would report
+it, for deciding whether another message can be appended to the
+image. Only messages written to consecutive pixels leave
+everything after End unused, so with scatter, spread, a mirror
+or density a message written from End may overwrite this one.
+*/
+type EncodeStats struct {
+	End            Point   `json:"end"`
+	MsgLen         int     `json:"msgLen"`
+	PixelsWritten  int     `json:"pixelsWritten"`
+	PixelsChanged  int     `json:"pixelsChanged"`
+	BitsFlipped    int     `json:"bitsFlipped"`
+	BitsWritten    int     `json:"bitsWritten"`
+	CapacityBits   int     `json:"capacityBits"`
+	CapacityUsed   float64 `json:"capacityUsed"`
+	BytesRemaining int     `json:"bytesRemaining"`
+}
+
+// EncodeWithStats is like Encode but also reports how much
+// the image was changed.
+func (e *Encoder) EncodeWithStats(src, dst, msg string, start Point) (stats EncodeStats, err error) {
+	_, err = e.encodeMsgFile(src, dst, start, []byte(msg), func(img draw.Image) (Point, error) {
+		stats, err = e.encodeImage(context.Background(), img, []byte(msg), start)
+		return stats.End, err
+	})
+	return stats, err
+}
+
+// EncodeImageWithStats is like EncodeImage but also reports
+// how much img was changed.
+func (e *Encoder) EncodeImageWithStats(img draw.Image, msg string, start Point) (EncodeStats, error) {
+	return e.encodeImage(context.Background(), img, []byte(msg), start)
+}
+
+/*
+EncodeResult is returned by EncodeDetailed and holds everything
+known about an encode in a form that can be marshalled to JSON,
+for tools that report on what they did. Along with the stats
+it has the point the message was written from, the settings
+of the encoder as given by String, and every pixel written to,
+in the order they were written, including those of the format
+header and length header. Pixels has an entry for each pixel so
+it can be large for long messages.
+*/
+type EncodeResult struct {
+	EncodeStats
+	Start    Point   `json:"start"`
+	Settings string  `json:"settings"`
+	Pixels   []Point `json:"pixels"`
+}
+
+// EncodeDetailed is like EncodeWithStats but returns an
+// EncodeResult.
+func (e *Encoder) EncodeDetailed(src, dst, msg string, start Point) (res EncodeResult, err error) {
+	_, err = e.encodeMsgFile(src, dst, start, []byte(msg), func(img draw.Image) (Point, error) {
+		res, err = e.encodeDetailed(context.Background(), img, []byte(msg), start)
+		return res.End, err
+	})
+	return res, err
+}
+
+// EncodeImageDetailed is like EncodeImageWithStats but returns
+// an EncodeResult.
+func (e *Encoder) EncodeImageDetailed(img draw.Image, msg string, start Point) (EncodeResult, error) {
+	return e.encodeDetailed(context.Background(), img, []byte(msg), start)
+}
+
+func (e *Encoder) encodeDetailed(ctx context.Context, img draw.Image, msg []byte, start Point) (res EncodeResult, err error) {
+
+	c, err := e.carrierFor(img)
+	if err != nil {
+		return res, err
+	}
+	if err = alphaCheck(img, e.activeChannels()); err != nil {
+		return res, err
+	}
+
+	p, stats, err := e.encodePlaced(ctx, c, msg, start)
+	res.EncodeStats = stats
+	if err != nil {
+		return res, err
+	}
+
+	res.Start = start
+	res.Settings = e.String()
+
+	bounds := c.bounds()
+	first := offsetFromMin(bounds, e.traversal, start)
+	res.Pixels = make([]Point, 0, e.formatPixels()+p.pixels)
+	for i := 0; i < e.formatPixels(); i++ {
+		res.Pixels = append(res.Pixels, pointAt(bounds, e.traversal, first+i))
+	}
+	for i := 0; i < p.pixels; i++ {
+		res.Pixels = append(res.Pixels, p.at(i))
+	}
+
+	if e.mirror {
+		s, _, _ := e.mirrorStart(bounds, start, p.end)
+		second, err := e.place(c, msg, s)
+		if err != nil {
+			return res, err
+		}
+		first := offsetFromMin(bounds, e.traversal, s)
+		for i := 0; i < e.formatPixels(); i++ {
+			res.Pixels = append(res.Pixels, pointAt(bounds, e.traversal, first+i))
+		}
+		for i := 0; i < second.pixels; i++ {
+			res.Pixels = append(res.Pixels, second.at(i))
+		}
+	}
+
+	return res, nil
+}
@@ -1,6 +1,6 @@
 /*
 Package steg provides steganographic encoding of messages
-inside of PNG files.
+inside of PNG, BMP and TIFF files.
 
 	src := "image.png"
 	dst := "image_with_msg.png"
@@ -37,328 +37,1657 @@ inside of PNG files.
 	}
 
 	fmt.Println(retrievedMsg)
-
 */
 package steg
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"image"
-	"image/color"
+	"image/draw"
 	"image/png"
-	"math"
+	"io"
+	"math/bits"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 /*
-Point represents a pixel coordinate in the image.
+Point represents a pixel coordinate in the image. Points are in
+the image's own coordinate space rather than relative to its top
+left corner, so for a sub-image whose bounds don't start at
+(0, 0), such as one returned by SubImage, the first pixel is
+img.Bounds().Min and points outside of its bounds are out of
+bounds even if they lie in the parent image.
 */
 type Point struct {
-	X int
-	Y int
+	X int `json:"x"`
+	Y int `json:"y"`
 }
 
-func (p1 *Point) before(p2 Point) bool {
-	if p1.Y >= p2.Y && p1.X > p2.X {
-		return false
-	}
-	return true
+/*
+Channel identifies one of the colour channels of a pixel.
+*/
+type Channel int
+
+const (
+	ChannelRed Channel = iota
+	ChannelGreen
+	ChannelBlue
+	ChannelAlpha
+)
+
+func (c Channel) valid() bool {
+	return c >= ChannelRed && c <= ChannelAlpha
 }
 
 /*
 Encoder has methods for writing and retrieving messages
-written in PNG images. It defaults to encoding messages in
-the least significant bit.
+written in lossless images. It defaults to encoding messages in
+the least significant bit of the red channel.
+
+Only an Encoder's Set methods modify it, and they copy any slices
+passed to them, so one Encoder may be used by several goroutines
+at once provided its settings aren't changed while it is in use.
+Goroutines that need different settings should each have their
+own Encoder.
 */
 type Encoder struct {
-	bit int
+	bit          int
+	channels     []Channel
+	lengthHeader bool
+	magic        []byte
+	passphrase   string
+	compression  bool
+	checksum     bool
+	traversal    Traversal
+	scatter      bool
+	scatterSeed  int64
+	spread       bool
+	depth        int
+	terminator   bool
+	createDirs   bool
+	keepChunks   bool
+	pngLevel     png.CompressionLevel
+	hopKey       string
+	hopMax       int
+	chroma       bool
+	channelBits  map[Channel]int
+	headerDepth  int
+	formatHeader bool
+	stride       int
+	phase        int
+	bitOrder     BitOrder
+	progress     func(done, total int)
+	logger       func(event string, fields map[string]any)
+
+	densityWindow    int
+	densityThreshold float64
+	skipTransparent  bool
+	ecc              ErrorCorrection
+	maxDecode        int
+	armor            Armor
+	sidecar          bool
+	verifyDisk       bool
+	parallelism      int
+	mirror           bool
+	metadata         *Metadata
+	preserveFormat   bool
+	preserveModTime  bool
+	parity           bool
+	decodedMetadata  *Metadata      // metadata read while decoding, if non-nil
+	corrected        *int           // bits corrected while decoding, if non-nil
+	corrupt          map[Point]bool // pixels failing parity while decoding, if non-nil
+	verified         *bool          // set once a checksum is verified while decoding, if non-nil
 }
 
 /*
 SetMsgBit specifies which bit each byte will use for its
-part of the message. If n is outside the range of 0-7
+part of the message. If n is outside the range of 0-15
 (inclusive) SetMsgBit will return an out of bounds error.
 The least significant bit is zero and by default message
 data will be written to this bit.
+
+Bits are counted from the least significant bit of the full
+sample, so for images with 16 bits per sample, such as
+*image.Gray16 and *image.NRGBA64, the default bit is the least
+significant of all 16 and the image keeps its full precision.
+Bits 8-15 only exist in those images; encoding or decoding an
+8-bit image with them returns an error.
+
+The bit depth (see SetBitDepth) counts upwards from n, and the
+bits used must lie either in bits 0-7, which every image has, or
+in bits 8-15. n is therefore also out of bounds if n plus the
+depth would run past bit 7 or, for an n of 8 or more, past bit
+15; with a depth of 3, for instance, the highest n below 8 is
+5. To move to a higher bit with a lower depth, call SetBitDepth
+first.
 */
 func (e *Encoder) SetMsgBit(n int) error {
-	if n < 0 || n > 7 {
-		return fmt.Errorf("msg bit out of bounds: got %d, wanted 0-7 inclusive", n)
+	if n < 0 || n > 15 {
+		return fmt.Errorf("msg bit out of bounds: got %d, wanted 0-15 inclusive", n)
+	}
+	if d := e.bitDepth(); !bitsFit(n, d) {
+		return fmt.Errorf("msg bit out of bounds: got %d, wanted 0-%d or 8-%d inclusive with bit depth %d", n, 8-d, 16-d, d)
 	}
 	e.bit = n
 	return nil
 }
 
 /*
-Encode takes the image at src and writes it to dst with msg
-stored inside it. The value of start is a pixel coordinate
-determining where the message will begin to be written.
+SetChannel specifies which colour channel of each pixel will
+hold its part of the message. If c is not one of ChannelRed,
+ChannelGreen, ChannelBlue or ChannelAlpha SetChannel will
+return an error. By default message data will be written to
+the red channel.
+*/
+func (e *Encoder) SetChannel(c Channel) error {
+	if !c.valid() {
+		return fmt.Errorf("invalid channel: got %d, wanted %d-%d inclusive", c, ChannelRed, ChannelAlpha)
+	}
+	e.channels = []Channel{c}
+	e.channelBits = nil
+	return nil
+}
 
-Each pixel of the image from start will contain one bit of msg
-until msg is fully written. This means that msg needs len(msg)*8
-pixels from start to store its entire payload. By default the
-one bit of msg per pixel is written to the least significant bit
-of the pixel's red channel.
+/*
+SetChannels specifies a list of colour channels that each
+pixel will use to hold consecutive bits of the message. Every
+channel in cs receives one bit, in the order given, before
+the next pixel is used; decoding walks the channels in the
+same order. Setting the red, green and blue channels therefore
+stores three bits of the message in each pixel. Channels not in
+cs are never written to, so the alpha channel, and with it the
+image's transparency, is left untouched unless it is listed.
+
+SetChannels returns an error if cs is empty, contains a value
+that is not a valid Channel or lists the same channel twice.
+*/
+func (e *Encoder) SetChannels(cs []Channel) error {
+	if len(cs) == 0 {
+		return errors.New("no channels specified")
+	}
+	var seen [ChannelAlpha + 1]bool
+	for _, c := range cs {
+		if !c.valid() {
+			return fmt.Errorf("invalid channel: got %d, wanted %d-%d inclusive", c, ChannelRed, ChannelAlpha)
+		}
+		if seen[c] {
+			return fmt.Errorf("duplicate channel: %d", c)
+		}
+		seen[c] = true
+	}
+	e.channels = append([]Channel(nil), cs...)
+	e.channelBits = nil
+	return nil
+}
 
-Encode returns end which is the coordinates of the first pixel
-after msg.
+/*
+SetLengthHeader specifies whether Encode prefixes msg with a
+header holding its length. With the header enabled messages can
+be read back with DecodeAuto, which only needs the start point.
+Decode also uses the header to discard any trailing bytes read
+after the message. The length is stored as an unsigned varint
+(see encoding/binary) so the header takes up 1 byte for messages
+under 128 bytes, 2 bytes under 16 KiB and so on. It is disabled
+by default.
+*/
+func (e *Encoder) SetLengthHeader(enabled bool) {
+	e.lengthHeader = enabled
+}
 
-Encode will return an error if the start or the end points
-of msg are outside the bounds of src. Supplying a zero length
-msg will also result in an error.
+/*
+SetMagic specifies a marker that Encode writes before msg (and
+before the length header if it is enabled). When decoding, the
+marker is checked and ErrNoMessage is returned if it is not
+present, which guards against treating the pixels of an image
+that holds no message as one. The marker also lets FindStart
+locate a message without knowing its start point. Passing a nil
+or empty marker disables it, which is the default.
 */
-func (e *Encoder) Encode(src, dst, msg string, start Point) (end Point, err error) {
+func (e *Encoder) SetMagic(marker []byte) {
+	e.magic = append([]byte(nil), marker...)
+}
 
-	if len(msg) == 0 {
-		return end, errors.New("msg is zero length")
+/*
+SetPassphrase specifies a passphrase used to encrypt msg before
+it is written to the image. The message is sealed with
+AES-256-GCM using a key derived from pw with scrypt; the random
+salt and nonce are stored in the image along with the
+ciphertext, which adds 44 bytes to the message. Decoding with
+a different passphrase returns ErrAuthentication. An empty
+passphrase disables encryption, which is the default.
+*/
+func (e *Encoder) SetPassphrase(pw string) {
+	e.passphrase = pw
+}
+
+/*
+SetCompression specifies whether msg is compressed with
+DEFLATE before it is written to the image (and before it is
+encrypted, when a passphrase is set). A 5 byte header holding
+a flag and the compressed length is written with the message.
+If compressing would not make msg smaller it is stored as is,
+so the cost of enabling compression is at most those 5 bytes.
+Compression is disabled by default.
+*/
+func (e *Encoder) SetCompression(enabled bool) {
+	e.compression = enabled
+}
+
+/*
+SetChecksum specifies whether a CRC-32 checksum is written
+after msg (after any compression and encryption). When decoding
+the checksum is recomputed and ErrChecksumMismatch is returned
+if it differs, catching messages corrupted by changes to the
+image. The checksum takes up 4 bytes and is disabled by default.
+*/
+func (e *Encoder) SetChecksum(enabled bool) {
+	e.checksum = enabled
+}
+
+func (e *Encoder) usesChannel(c Channel) bool {
+	for _, ch := range e.activeChannels() {
+		if ch == c {
+			return true
+		}
 	}
+	return false
+}
 
-	src, err = filepath.Abs(src)
-	if err != nil {
-		return end, err
+/*
+SetChannelBits specifies the channels that hold the message
+along with the msg bit of each, in place of SetChannels and
+SetMsgBit, so that for instance red can use bit 0 while green
+uses bit 1. Because maps are unordered the channels are used in
+the order red, green, blue then alpha, whatever order bits was
+built in. Any bit depth (see SetBitDepth) applies upwards from
+each channel's bit.
+
+SetChannelBits returns an error if bits is empty, contains a
+value that is not a valid Channel or a bit outside the range of
+0-7 (inclusive), or if the bit depth would take any bit past bit
+7. Calling SetChannel or SetChannels afterwards
+returns all channels to the bit set with SetMsgBit.
+*/
+func (e *Encoder) SetChannelBits(bits map[Channel]int) error {
+	if len(bits) == 0 {
+		return errors.New("no channels specified")
+	}
+	cb := make(map[Channel]int, len(bits))
+	for c, n := range bits {
+		if !c.valid() {
+			return fmt.Errorf("invalid channel: got %d, wanted %d-%d inclusive", c, ChannelRed, ChannelAlpha)
+		}
+		if n < 0 || n > 7 {
+			return fmt.Errorf("msg bit out of bounds for channel %d: got %d, wanted 0-7 inclusive", c, n)
+		}
+		if d := e.bitDepth(); !bitsFit(n, d) {
+			return fmt.Errorf("msg bit out of bounds for channel %d: got %d, wanted 0-%d inclusive with bit depth %d", c, n, 8-d, d)
+		}
+		cb[c] = n
+	}
+	var cs []Channel
+	for c := ChannelRed; c <= ChannelAlpha; c++ {
+		if _, ok := cb[c]; ok {
+			cs = append(cs, c)
+		}
+	}
+	e.channels = cs
+	e.channelBits = cb
+	return nil
+}
+
+// msgBit returns the lowest bit plane message bits are written
+// to in channel c.
+func (e *Encoder) msgBit(c Channel) int {
+	if n, ok := e.channelBits[c]; ok {
+		return n
 	}
+	return e.bit
+}
 
-	dst, err = filepath.Abs(dst)
-	if err != nil {
-		return end, err
+// msgBits returns msgBit for each of cs.
+func (e *Encoder) msgBits(cs []Channel) []int {
+	bits := make([]int, len(cs))
+	for i, c := range cs {
+		bits[i] = e.msgBit(c)
 	}
+	return bits
+}
 
-	r, err := os.Open(src)
-	if err != nil {
-		return end, err
+func (e *Encoder) activeChannels() []Channel {
+	if e.chroma {
+		return chromaChannels
 	}
-	defer r.Close()
+	if e.parity {
+		return parityChannels
+	}
+	if len(e.channels) == 0 {
+		return []Channel{ChannelRed}
+	}
+	return e.channels
+}
 
-	p, err := png.Decode(r)
-	if err != nil {
-		return end, err
+/*
+SetBitDepth specifies how many bits of each channel hold
+message data. The bits used start at the msg bit (see
+SetMsgBit) and go upwards, so with the default msg bit of zero
+a depth of 2 uses the two least significant bits. If n is
+outside the range of 1-4 (inclusive), or would take the bits
+used by any channel past bit 7, or past bit 15 for a msg bit of
+8 or more (see SetMsgBit), SetBitDepth returns an error. The
+default depth is 1.
+
+Each extra bit multiplies the capacity of the image but also
+the amount of noise added to it: at a depth of 1 a sample
+changes by at most 1, at a depth of 4 by up to 15, which
+becomes visible in smooth areas of the image. Once the channels
+and depth give more than 8 bits per pixel Encode needs a length
+header or null terminator to mark where the message ends; see
+Decode.
+*/
+func (e *Encoder) SetBitDepth(n int) error {
+	if n < 1 || n > 4 {
+		return fmt.Errorf("bit depth out of bounds: got %d, wanted 1-4 inclusive", n)
+	}
+	for _, c := range e.activeChannels() {
+		if bit := e.msgBit(c); !bitsFit(bit, n) {
+			return fmt.Errorf("bit depth out of bounds: got %d, wanted 1-%d inclusive with msg bit %d", n, topBit(bit)+1-bit, bit)
+		}
 	}
+	e.depth = n
+	return nil
+}
 
-	img, ok := p.(*image.RGBA)
-	if !ok {
-		return end, errors.New("failed type assertion from image.Image to image.RGBA")
+func (e *Encoder) bitDepth() int {
+	if e.depth == 0 {
+		return 1
 	}
+	return e.depth
+}
 
-	bounds := img.Bounds()
-	end = pointAtOffset(bounds, start, len(msg)*8)
+func (e *Encoder) checkBits() error {
+	for _, c := range e.activeChannels() {
+		if bit := e.msgBit(c); !bitsFit(bit, e.bitDepth()) {
+			return fmt.Errorf("msg bit %d with bit depth %d runs past bit %d", bit, e.bitDepth(), topBit(bit))
+		}
+	}
+	if e.hopKey != "" && e.bitDepth() > 1 {
+		return errors.New("bit hopping requires a bit depth of 1")
+	}
+	return nil
+}
 
-	if !inBounds(bounds, start) {
-		return end, errors.New("start point out of bounds")
+/*
+checkEnd returns an error if a message written with the
+encoder's settings could be decoded with extra bytes on the end,
+which happens when a pixel holds more bits than a byte is written
+as and nothing in the payload marks where the message ends.
+*/
+func (e *Encoder) checkEnd() error {
+	if bpp := e.bitsPerPixel(); bpp > e.byteBits() && !e.lengthHeader && !e.terminator {
+		return fmt.Errorf("%d bits per pixel needs a length header or null terminator to mark the end of msg", bpp)
 	}
-	if !inBounds(bounds, end) {
-		return end, errors.New("end point out of bounds")
+	return nil
+}
+
+// bitsFit reports whether depth bits upwards from bit stay within
+// the byte of the sample that bit is in, so that a message below
+// bit 8 fits 8-bit images as well as 16-bit ones.
+func bitsFit(bit, depth int) bool {
+	return bit+depth-1 <= topBit(bit)
+}
+
+// topBit returns the highest bit of the byte of a 16-bit sample
+// that bit is in.
+func topBit(bit int) int {
+	if bit < 8 {
+		return 7
 	}
+	return 15
+}
 
-	var tmp [8]bool
-	var i uint
-	offset := offsetFromMin(bounds, start)
+func (e *Encoder) bitsPerPixel() int {
+	return len(e.activeChannels()) * e.bitDepth()
+}
+
+/*
+pixelsFor returns how many pixels are needed to store n bits
+given the encoder's channel settings.
+*/
+func (e *Encoder) pixelsFor(bits int) int {
+	bpp := e.bitsPerPixel()
+	return (bits + bpp - 1) / bpp
+}
 
-outer:
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+/*
+Encode takes the image at src and writes it to dst with msg
+stored inside it. The value of start is a pixel coordinate
+determining where the message will begin to be written.
 
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+Each pixel of the image from start will contain one bit of msg
+per channel (or more, see SetBitDepth) until msg is fully
+written. This means that msg needs
 
-			if x < start.X || y < start.Y {
-				i++
-				continue
-			}
+	ceil(len(msg)*8 / (len(channels)*depth))
 
-			if x == end.X && y == end.Y {
-				break outer
-			}
+pixels from start to store its entire payload. By default the
+one bit of msg per pixel is written to the least significant bit
+of the pixel's red channel. The channels used can be changed with
+SetChannel or SetChannels. Pixels are visited left to right and
+top to bottom, wrapping to the start of the next row, unless a
+different order is chosen with SetTraversal.
 
-			mod := i % 8
+Encode returns end which is the coordinates of the first pixel
+after msg.
 
-			if mod == 0 {
-				byteToBits(&tmp, msg[(i-offset)/8])
-			}
+src may be a PNG, BMP, TIFF or JPEG image. dst is written as a
+BMP if its extension is .bmp, a TIFF if it is .tif or .tiff and
+a PNG otherwise. The alpha channel can't be used with BMP
+output. JPEG images are converted to RGBA before msg is
+written; dst must not be re-saved as a JPEG as lossy compression
+destroys the message.
+
+Encode will return an error if the start point is outside the
+bounds of src or if msg doesn't fit between start and the end of
+the image, in which case the error wraps ErrMsgTooLarge and says
+how many more pixels were needed. Supplying a zero length msg
+will also result in an error.
+*/
+func (e *Encoder) Encode(src, dst, msg string, start Point) (end Point, err error) {
+	return e.EncodeContext(context.Background(), src, dst, msg, start)
+}
 
-			r, g, b, a := img.At(x, y).RGBA()
+/*
+EncodeContext is like Encode but stops early with ctx.Err() if
+ctx is cancelled while msg is being written, in which case dst
+is not created.
+*/
+func (e *Encoder) EncodeContext(ctx context.Context, src, dst, msg string, start Point) (end Point, err error) {
+	return e.encodeMsgFile(src, dst, start, []byte(msg), func(img draw.Image) (Point, error) {
+		stats, err := e.encodeImage(ctx, img, []byte(msg), start)
+		return stats.End, err
+	})
+}
 
-			if tmp[mod] { // set bit
-				r |= uint32(pow(2, e.bit))
-			} else { // clear bit
-				r = uint32(byte(r) & ^(byte(pow(2, e.bit))))
-			}
+/*
+EncodeBytes is like Encode but takes msg as a byte slice, so
+it may hold arbitrary binary data rather than text.
+*/
+func (e *Encoder) EncodeBytes(src, dst string, msg []byte, start Point) (end Point, err error) {
+	return e.encodeMsgFile(src, dst, start, msg, func(img draw.Image) (Point, error) {
+		stats, err := e.encodeImage(context.Background(), img, msg, start)
+		return stats.End, err
+	})
+}
+
+/*
+EncodeAndVerify is like Encode but before saving dst it decodes
+the message back out of the encoded image and compares it with
+msg, returning ErrVerifyFailed if they differ. This replaces the
+usual pattern of calling Decode on dst after Encode without
+reading dst back from disk.
+*/
+func (e *Encoder) EncodeAndVerify(src, dst, msg string, start Point) (end Point, err error) {
+	return e.encodeMsgFile(src, dst, start, []byte(msg), func(img draw.Image) (Point, error) {
 
-			img.Set(x, y, color.RGBA{
-				uint8(r),
-				uint8(g),
-				uint8(b),
-				uint8(a),
-			})
+		stats, err := e.encodeImage(context.Background(), img, []byte(msg), start)
+		end := stats.End
+		if err != nil {
+			return end, err
+		}
 
-			i++
+		got, err := e.decodeImage(context.Background(), img, start, end)
+		if err != nil {
+			return end, fmt.Errorf("%w: %v", ErrVerifyFailed, err)
+		}
+		if string(got) != msg {
+			return end, ErrVerifyFailed
 		}
+
+		return end, nil
+	})
+}
+
+/*
+EncodeStream is like Encode but reads the image from src and
+writes the PNG image containing msg to dst. Nothing is
+written to dst if an error occurs before encoding the output.
+*/
+func (e *Encoder) EncodeStream(dst io.Writer, src io.Reader, msg string, start Point) (end Point, err error) {
+	return e.encodeStream(dst, src, e.encodePNG, func(img draw.Image) (Point, error) {
+		stats, err := e.encodeImage(context.Background(), img, []byte(msg), start)
+		return stats.End, err
+	})
+}
+
+/*
+EncodeToBytes is like Encode but returns the PNG image containing
+msg rather than saving it, for instance to be sent in an HTTP
+response without going through a temporary file.
+*/
+func (e *Encoder) EncodeToBytes(src, msg string, start Point) (data []byte, end Point, err error) {
+
+	src, err = filepath.Abs(src)
+	if err != nil {
+		return nil, end, err
 	}
 
-	w, err := os.Create(dst)
+	r, err := os.Open(src)
 	if err != nil {
-		return end, err
+		return nil, end, err
 	}
-	defer w.Close()
+	defer r.Close()
 
-	err = png.Encode(w, p)
+	var buf bytes.Buffer
+	end, err = e.EncodeStream(&buf, r, msg, start)
 	if err != nil {
-		return end, err
+		return nil, end, err
 	}
 
-	return end, nil
+	return buf.Bytes(), end, nil
 }
 
 /*
-Decode reads src from start to end and extracts msg.
+SetCreateDirs specifies whether Encode and the other methods
+that save an image create the directory dst is saved in, along
+with any missing parents, when it doesn't exist. Otherwise they
+return an error before reading src if the directory is missing,
+which is the default.
+*/
+func (e *Encoder) SetCreateDirs(enabled bool) {
+	e.createDirs = enabled
+}
 
-Returns an error if start or end are outside the
-boundaries of src or if start does not precede end.
+// checkDstDir makes sure the directory dst will be saved in
+// exists so that a missing directory is reported before src
+// is encoded.
+func (e *Encoder) checkDstDir(dst string) error {
+
+	dir := filepath.Dir(dst)
+
+	if e.createDirs {
+		return os.MkdirAll(dir, 0777)
+	}
+
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("dst directory: %w", err)
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("dst directory %s is not a directory", dir)
+	}
+
+	return nil
+}
+
+/*
+encodeFile decodes the image at src, passes it to fn to
+have a message written to it and saves the result to dst. dst
+is only created once fn has succeeded.
 */
-func (e *Encoder) Decode(src string, start, end Point) (msg string, err error) {
+func (e *Encoder) encodeFile(src, dst string, fn func(draw.Image) (Point, error)) (end Point, err error) {
 
-	if !start.before(end) {
-		return msg, errors.New("start point does not precede end point")
+	enc, err := e.encoderFor(dst)
+	if err != nil {
+		return end, err
 	}
 
 	src, err = filepath.Abs(src)
 	if err != nil {
-		return msg, err
+		return end, err
+	}
+
+	dst, err = filepath.Abs(dst)
+	if err != nil {
+		return end, err
+	}
+
+	if err = e.checkDstDir(dst); err != nil {
+		return end, err
 	}
 
 	r, err := os.Open(src)
 	if err != nil {
-		return msg, err
+		return end, err
 	}
 	defer r.Close()
 
-	p, err := png.Decode(r)
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	end, err = e.encodeStream(buf, r, enc, fn)
 	if err != nil {
-		return msg, err
+		return end, err
 	}
 
-	img, ok := p.(*image.RGBA)
-	if !ok {
-		return msg, errors.New("failed type assertion from image.Image to image.RGBA")
+	w, err := os.Create(dst)
+	if err != nil {
+		return end, err
 	}
 
-	bounds := img.Bounds()
-	if !inBounds(bounds, start) {
-		return msg, errors.New("start point out of bounds")
+	_, err = buf.WriteTo(w)
+	if cerr := w.Close(); err == nil {
+		err = cerr
 	}
-	if !inBounds(bounds, end) {
-		return msg, errors.New("end point out of bounds")
+	if err != nil {
+		return end, err
 	}
 
-	var tmp [8]bool
-	var i uint
-
-outer:
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-
-			if x < start.X || y < start.Y {
-				i++
-				continue
-			}
+	return end, e.keepModTime(src, dst)
+}
 
-			if x == end.X && y == end.Y {
-				break outer
-			}
+/*
+encodeMsgFile is like encodeFile for fn writing msg from start.
+Once dst has been saved it is checked against msg if on-disk
+verification is enabled and its sidecar is written if sidecars
+are enabled.
+*/
+func (e *Encoder) encodeMsgFile(src, dst string, start Point, msg []byte, fn func(draw.Image) (Point, error)) (end Point, err error) {
 
-			mod := i % 8
+	end, err = e.encodeFile(src, dst, fn)
+	if err != nil {
+		return end, err
+	}
 
-			r, _, _, _ := img.At(x, y).RGBA()
+	if err = e.verifyFile(dst, msg, start, end); err != nil {
+		return end, err
+	}
 
-			if byte(r)&byte(pow(2, e.bit)) == 0 {
-				tmp[mod] = false
-			} else {
-				tmp[mod] = true
-			}
+	return end, e.writeSidecar(dst, start, end, len(msg))
+}
 
-			if mod == 8-1 {
-				n := bitsToByte(tmp)
-				msg += string(n)
-			}
+func (e *Encoder) encodeStream(dst io.Writer, src io.Reader, enc imageEncoder, fn func(draw.Image) (Point, error)) (end Point, err error) {
 
-			i++
+	if e.keepChunks || e.preserveFormat {
+		data, err := io.ReadAll(src)
+		if err != nil {
+			return end, err
+		}
+		src = bytes.NewReader(data)
+		if e.keepChunks {
+			enc = keepChunks(enc, pngChunks(data))
+		}
+		if e.preserveFormat {
+			enc = preserveFormat(enc, data)
 		}
 	}
 
-	return msg, nil
-}
+	p, _, err := image.Decode(src)
+	if err != nil {
+		return end, err
+	}
 
-func inBounds(r image.Rectangle, p Point) bool {
-	if p.X < r.Min.X {
-		return false
+	img, ok := fromJPEG(p).(draw.Image)
+	if !ok {
+		return end, fmt.Errorf("%w %T", ErrUnsupportedImage, p)
 	}
-	if p.Y < r.Min.Y {
-		return false
+
+	if e.usesChannel(ChannelAlpha) {
+		img = nonPremultiplied(img)
 	}
-	if p.X >= r.Max.X {
-		return false
+
+	end, err = fn(img)
+	if err != nil {
+		return end, err
 	}
-	if p.Y >= r.Max.Y {
-		return false
+
+	err = enc(dst, img)
+	if err != nil {
+		return end, err
 	}
-	return true
-}
 
-func offsetFromMin(r image.Rectangle, p Point) uint {
-	width := uint(r.Max.X) - uint(r.Min.X)
-	return (uint(p.Y) * uint(width)) + uint(p.X)
+	return end, nil
 }
 
-func pointAtOffset(r image.Rectangle, p Point, offset int) Point {
-
-	width := r.Max.X - r.Min.X
-	rows := offset / width
-	p.Y += rows
+/*
+EncodeImage is like Encode but writes msg directly into img,
+modifying it in place.
+
+The supported image types are *image.RGBA, *image.NRGBA,
+*image.Gray and *image.Paletted along with their 16-bit
+counterparts *image.RGBA64, *image.NRGBA64 and *image.Gray16.
+For 16-bit images all 16 bits of each sample can hold the
+message (see SetMsgBit) and the image keeps its bit depth. Gray
+and paletted images have only one sample per pixel so the
+encoder must be using a single channel other than ChannelAlpha.
+For paletted images the message is written to each pixel's
+palette index; if this results in an index past the end of the
+palette the palette is extended with copies of the pixel's
+original colour.
+
+The alpha channel of premultiplied images (*image.RGBA and
+*image.RGBA64) cannot be written to as changing it would change
+the colour of the pixel. Encode and EncodeStream get around this
+by converting such images to *image.NRGBA or *image.NRGBA64
+when the alpha channel is used.
+*/
+func (e *Encoder) EncodeImage(img draw.Image, msg string, start Point) (end Point, err error) {
+	stats, err := e.encodeImage(context.Background(), img, []byte(msg), start)
+	return stats.End, err
+}
 
-	offset -= rows * width
+func (e *Encoder) encodeImage(ctx context.Context, img draw.Image, msg []byte, start Point) (stats EncodeStats, err error) {
 
-	if p.X+offset > r.Max.X {
-		p.Y++
-		offset -= r.Max.X - p.X
-		p.X = r.Min.X
+	c, err := e.carrierFor(img)
+	if err != nil {
+		return stats, err
+	}
+	if err = alphaCheck(img, e.activeChannels()); err != nil {
+		return stats, err
 	}
 
-	p.X += offset
-
-	return p
+	return e.encodeCarrier(ctx, c, msg, start)
 }
 
-func bitsToByte(bits [8]bool) (b byte) {
+func (e *Encoder) encodeCarrier(ctx context.Context, c carrier, msg []byte, start Point) (stats EncodeStats, err error) {
+	_, stats, err = e.encodePlaced(ctx, c, msg, start)
+	return stats, err
+}
 
-	for i, bit := range bits {
+// encodePlaced is encodeCarrier but also returns where the
+// message was written.
+func (e *Encoder) encodePlaced(ctx context.Context, c carrier, msg []byte, start Point) (p placement, stats EncodeStats, err error) {
 
-		// Bit position; e.g. 128, 64, 32, 16, etc
-		pos := pow(2, 7-i)
+	if e.logger != nil {
+		t := time.Now()
+		defer func() { e.logEncode(t, start, stats, err) }()
+	}
 
-		if bit {
-			b += byte(pos)
+	p, err = e.place(c, msg, start)
+	if err != nil {
+		return p, EncodeStats{End: p.end}, err
+	}
+	if e.mirror {
+		if _, _, err = e.mirrorStart(c.bounds(), p.start, p.end); err != nil {
+			return p, EncodeStats{End: p.end}, err
 		}
 	}
 
-	return b
-}
+	stats, err = e.write(ctx, c, p)
+	stats.End = p.end
+	stats.MsgLen = len(msg)
+	stats.BitsWritten = len(p.payload) * e.byteBits()
+	stats.CapacityBits = p.avail * e.bitsPerPixel()
+	stats.CapacityUsed = float64(stats.BitsWritten) / float64(stats.CapacityBits)
 
-func byteToBits(bits *[8]bool, b byte) {
+	if e.mirror && err == nil {
+		stats, err = e.encodeMirror(ctx, c, msg, p, stats)
+	}
 
-	for i := range bits {
+	// The end point is always in bounds as the last pixel is
+	// never written to, so remaining can't fail.
+	_, n, _ := e.remaining(c.bounds(), p.end)
+	stats.BytesRemaining = e.msgBytes(n)
+
+	return p, stats, err
+}
 
-		// Bit position; e.g. 128, 64, 32, 16, etc
-		pos := pow(2, 7-i)
+// placement records where in a carrier a framed message is
+// to be written.
+type placement struct {
+	start   Point
+	payload []byte
+	hdr     int
+	pixels  int
+	avail   int // pixels the message could have used
+	at      func(int) Point
+	end     Point
+}
 
-		if int(b)-pos >= 0 {
-			bits[i] = true
-			b -= byte(pos)
-		} else {
-			bits[i] = false
+// place frames msg and works out where it will be written
+// without modifying c.
+func (e *Encoder) place(c carrier, msg []byte, start Point) (p placement, err error) {
+
+	if len(msg) == 0 {
+		return p, ErrMsgEmpty
+	}
+	if err = e.checkEnd(); err != nil {
+		return p, err
+	}
+
+	p.payload, p.hdr, err = e.frame(msg)
+	if err != nil {
+		return p, err
+	}
+
+	bounds := c.bounds()
+	if !inBounds(bounds, start) {
+		return p, ErrStartOutOfBounds
+	}
+
+	p.pixels = e.payloadPixels(len(p.payload), p.hdr)
+	if err = e.checkFits(bounds, start, e.formatPixels()+p.pixels); err != nil {
+		return p, err
+	}
+
+	p.start = start
+	start = e.bodyStart(bounds, start)
+
+	p.avail = bounds.Dx()*bounds.Dy() - offsetFromMin(bounds, e.traversal, start) - 1
+
+	set := e.pixels(c, bounds, start)
+	if set != nil {
+		if err = checkSetFits(set, p.pixels); err != nil {
+			return p, err
 		}
+		p.avail = set.len()
+	}
+	e.logCapacity(p.start, p.avail, p.avail*e.bitsPerPixel()/e.byteBits())
+
+	p.at, p.end, err = e.walk(bounds, start, set, p.hdr, p.pixels)
+	if err != nil {
+		return p, err
+	}
+	if !inBounds(bounds, p.end) {
+		return p, ErrEndOutOfBounds
+	}
+
+	return p, nil
+}
+
+// write writes a placed message to c.
+func (e *Encoder) write(ctx context.Context, c carrier, p placement) (stats EncodeStats, err error) {
+
+	if e.formatHeader {
+		if stats, err = e.writeFormatHeader(ctx, c, p.start); err != nil {
+			return stats, err
+		}
+	}
+
+	body, err := e.writePayload(ctx, c, p.pixels, p.at, p.payload, p.hdr)
+
+	stats.PixelsWritten += body.PixelsWritten
+	stats.PixelsChanged += body.PixelsChanged
+	stats.BitsFlipped += body.BitsFlipped
+
+	return stats, err
+}
+
+/*
+Decode reads src from start to end and extracts msg.
+
+Returns an error if start or end are outside the
+boundaries of src or if start does not precede end.
+If the encoder has a magic marker (see SetMagic) that
+is not found at start ErrNoMessage is returned.
+
+Only whole bytes are decoded. When the pixels from start to end
+hold a number of bits that isn't a multiple of 8 the remaining
+bits are the unused part of the last pixel Encode wrote to and
+are discarded. With more than 8 bits per pixel (see SetChannels
+and SetBitDepth) that unused part can span a whole byte, and as
+the end point alone can't say how much of the last pixel was
+used Encode then requires a length header or null terminator
+(see SetLengthHeader and SetNullTerminator).
+*/
+func (e *Encoder) Decode(src string, start, end Point) (msg string, err error) {
+	return e.DecodeContext(context.Background(), src, start, end)
+}
+
+/*
+DecodeContext is like Decode but stops early with ctx.Err() if
+ctx is cancelled while msg is being read.
+*/
+func (e *Encoder) DecodeContext(ctx context.Context, src string, start, end Point) (msg string, err error) {
+
+	img, err := readImage(src)
+	if err != nil {
+		return msg, err
 	}
+
+	b, err := e.decodeImage(ctx, img, start, end)
+	return string(b), err
+}
+
+/*
+DecodeBytes is like Decode but returns msg as a byte slice.
+*/
+func (e *Encoder) DecodeBytes(src string, start, end Point) (msg []byte, err error) {
+
+	img, err := readImage(src)
+	if err != nil {
+		return msg, err
+	}
+
+	return e.decodeImage(context.Background(), img, start, end)
+}
+
+/*
+DecodeStream is like Decode but reads the image from src.
+*/
+func (e *Encoder) DecodeStream(src io.Reader, start, end Point) (msg string, err error) {
+
+	p, _, err := image.Decode(src)
+	if err != nil {
+		return msg, err
+	}
+
+	return e.DecodeImage(p, start, end)
+}
+
+// DecodeFromBytes is like Decode but reads the image from data,
+// such as the bytes returned by EncodeToBytes.
+func (e *Encoder) DecodeFromBytes(data []byte, start, end Point) (msg string, err error) {
+	return e.DecodeStream(bytes.NewReader(data), start, end)
+}
+
+/*
+DecodeImage is like Decode but reads msg directly from img. It
+supports the same image types as EncodeImage.
+*/
+func (e *Encoder) DecodeImage(img image.Image, start, end Point) (msg string, err error) {
+	b, err := e.decodeImage(context.Background(), img, start, end)
+	return string(b), err
 }
 
-func pow(x, y int) int {
-	return int(math.Pow(float64(x), float64(y)))
+func (e *Encoder) decodeImage(ctx context.Context, img image.Image, start, end Point) (msg []byte, err error) {
+
+	c, err := e.carrierFor(img)
+	if err != nil {
+		return msg, err
+	}
+
+	return e.decodeCarrier(ctx, c, start, end)
+}
+
+func (e *Encoder) decodeCarrier(ctx context.Context, c carrier, start, end Point) (msg []byte, err error) {
+
+	if e.mirror {
+		return e.decodeMirror(ctx, c, start, end)
+	}
+
+	if e.formatHeader {
+		d, s, err := e.readFormatHeader(ctx, c, start)
+		if err != nil {
+			return msg, err
+		}
+		return d.decodeCarrier(ctx, c, s, end)
+	}
+
+	bounds := c.bounds()
+	pixels, err := e.span(bounds, start, end)
+	if err != nil {
+		return msg, err
+	}
+
+	// Only the usable pixels before end hold the message.
+	set := e.pixels(c, bounds, start)
+	if set != nil {
+		pixels = set.before(pixels)
+		if pixels*e.bitsPerPixel() < e.byteBits() {
+			return msg, fmt.Errorf("%d usable pixels from start to end hold less than a byte", pixels)
+		}
+	}
+
+	// Spread messages can only be located via their length
+	// header.
+	if e.spread {
+		return e.decodeAuto(ctx, c, start)
+	}
+
+	if e.logger != nil {
+		t := e.logDecodeStart(map[string]any{"start": start, "end": end, "pixels": pixels})
+		defer e.logDecodeDone(t, start, &msg, &err)
+	}
+
+	// Scattering and a separate header depth both start the
+	// body after the header, whose size depends on the length
+	// it holds.
+	hdr := len(e.magic)
+	if (e.scatter || e.headerDepth != 0) && e.lengthHeader {
+		if _, hdr, err = e.readHeader(ctx, c, start); err != nil {
+			return msg, err
+		}
+	}
+
+	if err = e.checkDecodeLimit(e.payloadBytes(pixels, hdr)); err != nil {
+		return msg, err
+	}
+
+	at, _, err := e.walk(bounds, start, set, hdr, pixels)
+	if err != nil {
+		return msg, err
+	}
+
+	data, err := e.readPayload(ctx, c, pixels, at, hdr)
+	if err != nil {
+		return msg, err
+	}
+
+	return e.unframe(data)
+}
+
+// span returns how many pixels lie between start and end,
+// checking that they hold at least one byte.
+func (e *Encoder) span(bounds image.Rectangle, start, end Point) (pixels int, err error) {
+	if !inBounds(bounds, start) {
+		return 0, ErrStartOutOfBounds
+	}
+	if !inBounds(bounds, end) {
+		return 0, ErrEndOutOfBounds
+	}
+	pixels = offsetFromMin(bounds, e.traversal, end) - offsetFromMin(bounds, e.traversal, start)
+	if pixels <= 0 {
+		return 0, ErrStartAfterEnd
+	}
+	if pixels*e.bitsPerPixel() < e.byteBits() {
+		return 0, fmt.Errorf("%d pixels from start to end hold less than a byte", pixels)
+	}
+	return pixels, nil
+}
+
+/*
+DecodeAuto reads a message from src that was written by an
+encoder with the length header enabled (see SetLengthHeader).
+Unlike Decode the end point does not need to be known; it is
+worked out from the length stored at start.
+
+DecodeAuto returns an error if the encoder does not have the
+length header enabled, ErrNoMessage if the encoder has a magic
+marker that is not found (see SetMagic), or an error if start,
+or the end point implied by the length header, is outside the
+bounds of src.
+*/
+func (e *Encoder) DecodeAuto(src string, start Point) (msg string, err error) {
+
+	img, err := readImage(src)
+	if err != nil {
+		return msg, err
+	}
+
+	return e.DecodeAutoImage(img, start)
+}
+
+/*
+DecodeAutoImage is like DecodeAuto but reads msg directly
+from img.
+*/
+func (e *Encoder) DecodeAutoImage(img image.Image, start Point) (msg string, err error) {
+	b, err := e.decodeAutoImage(img, start)
+	return string(b), err
+}
+
+func (e *Encoder) decodeAutoImage(img image.Image, start Point) (msg []byte, err error) {
+
+	if !e.lengthHeader && !e.formatHeader {
+		return msg, errors.New("length header is not enabled")
+	}
+
+	c, err := e.carrierFor(img)
+	if err != nil {
+		return msg, err
+	}
+
+	return e.decodeAuto(context.Background(), c, start)
+}
+
+func (e *Encoder) decodeAuto(ctx context.Context, c carrier, start Point) (msg []byte, err error) {
+
+	if e.formatHeader {
+		d, s, err := e.readFormatHeader(ctx, c, start)
+		if err != nil {
+			return msg, err
+		}
+		if !d.lengthHeader {
+			return msg, errors.New("message was encoded without a length header")
+		}
+		return d.decodeAuto(ctx, c, s)
+	}
+
+	if e.logger != nil {
+		t := e.logDecodeStart(map[string]any{"start": start})
+		defer e.logDecodeDone(t, start, &msg, &err)
+	}
+
+	n, hdr, err := e.readHeader(ctx, c, start)
+	if err != nil {
+		return msg, err
+	}
+	if err = e.checkDecodeLimit(hdr + n); err != nil {
+		return msg, err
+	}
+
+	bounds := c.bounds()
+	pixels := e.payloadPixels(hdr+n, hdr)
+
+	at, end, err := e.walk(bounds, start, e.pixels(c, bounds, start), hdr, pixels)
+	if err != nil {
+		return msg, err
+	}
+	if !inBounds(bounds, end) {
+		return msg, ErrEndOutOfBounds
+	}
+
+	data, err := e.readPayload(ctx, c, pixels, at, hdr)
+	if err != nil {
+		return msg, err
+	}
+
+	return e.unframe(data)
+}
+
+/*
+readHeader reads the magic marker and length header written at
+start. It returns the length held by the header, which is zero
+when the header is disabled, and the size of the marker and
+header together. ErrNoMessage is returned if the marker doesn't
+match.
+*/
+func (e *Encoder) readHeader(ctx context.Context, c carrier, start Point) (n, hdr int, err error) {
+
+	if e.formatHeader {
+		d, s, err := e.readFormatHeader(ctx, c, start)
+		if err != nil {
+			return 0, 0, err
+		}
+		return d.readHeader(ctx, c, s)
+	}
+
+	bounds := c.bounds()
+	if !inBounds(bounds, start) {
+		return 0, 0, ErrStartOutOfBounds
+	}
+
+	// The header is never scattered or spread so it can be read
+	// from consecutive pixels. Its size isn't known until the
+	// varint has been read so read enough for the largest
+	// varint, or as much of it as fits before the last pixel.
+	first := offsetFromMin(bounds, e.traversal, start)
+	avail := bounds.Dx()*bounds.Dy() - first - 1
+
+	offset := func(i int) int { return i }
+	if set := e.pixels(c, bounds, start); set != nil {
+		avail = set.len()
+		offset = set.offset
+	}
+
+	size := len(e.magic)
+	if e.lengthHeader {
+		size += maxLengthHeaderSize
+	}
+
+	// The read runs past the header into pixels that may never
+	// have been written to, and the header is read again along
+	// with the rest of the payload, so neither corrections nor
+	// parity failures are counted here.
+	probe := *e
+	probe.corrected = nil
+	probe.corrupt = nil
+	h := &probe
+	if e.headerDepth != 0 {
+		h = probe.header()
+	}
+
+	pixels := h.pixelsFor(size * e.byteBits())
+	if pixels > avail {
+		pixels = avail
+	}
+
+	data, err := h.readMsg(ctx, c, pixels, func(i int) Point {
+		return pointAt(bounds, e.traversal, first+offset(i))
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(data) < len(e.magic) {
+		return 0, 0, fmt.Errorf("length header %w", ErrEndOutOfBounds)
+	}
+	if err = e.checkMagic(data); err != nil {
+		return 0, 0, err
+	}
+	if !e.lengthHeader {
+		return 0, len(e.magic), nil
+	}
+
+	v, k := binary.Uvarint(data[len(e.magic):])
+	if k == 0 {
+		return 0, 0, fmt.Errorf("length header %w", ErrEndOutOfBounds)
+	}
+	if k < 0 {
+		return 0, 0, errors.New("invalid length header")
+	}
+
+	// Lengths that can't fit are rejected here so that later
+	// pixel counts can't overflow.
+	hdr = len(e.magic) + k
+	capacity := uint64(e.payloadBytes(avail, hdr))
+	if v > capacity || v+uint64(hdr) > capacity {
+		return 0, 0, fmt.Errorf("%w: length header holds %d bytes", ErrEndOutOfBounds, v)
+	}
+
+	return int(v), hdr, nil
+}
+
+// checkSettings returns an error if any of the encoder's
+// settings can't be used together.
+func (e *Encoder) checkSettings() error {
+	if err := e.checkBits(); err != nil {
+		return err
+	}
+	if err := e.checkLayout(); err != nil {
+		return err
+	}
+	if err := e.checkDensity(); err != nil {
+		return err
+	}
+	if err := e.checkHeaderDepth(); err != nil {
+		return err
+	}
+	if err := e.checkFormatHeader(); err != nil {
+		return err
+	}
+	if err := e.checkStride(); err != nil {
+		return err
+	}
+	if err := e.checkSkipTransparent(); err != nil {
+		return err
+	}
+	if err := e.checkErrorCorrection(); err != nil {
+		return err
+	}
+	if err := e.checkParity(); err != nil {
+		return err
+	}
+	if err := e.checkMirror(); err != nil {
+		return err
+	}
+	if err := e.checkMetadata(); err != nil {
+		return err
+	}
+	return e.checkTerminator()
+}
+
+/*
+carrierFor returns a carrier for img after checking that the
+encoder's settings are usable with it.
+*/
+func (e *Encoder) carrierFor(img image.Image) (carrier, error) {
+	if err := e.checkSettings(); err != nil {
+		return nil, err
+	}
+	if err := checkImage(img); err != nil {
+		return nil, err
+	}
+	c, err := newCarrier(img)
+	if err != nil {
+		return nil, err
+	}
+	if e.chroma {
+		c = chromaCarrier{c, byte(e.planeMask())}
+	}
+	if err = c.check(e.activeChannels()); err != nil {
+		return nil, err
+	}
+	if top := 15 - bits.LeadingZeros16(e.planeMask()); top >= c.sampleBits() {
+		return nil, fmt.Errorf("msg bit %d is outside the %d-bit samples of %T", top, c.sampleBits(), img)
+	}
+	return c, nil
+}
+
+// ctxCheckInterval is how many pixels are processed between
+// checks for cancellation.
+const ctxCheckInterval = 4096
+
+// writeMsg returns stats without End or MsgLen set.
+func (e *Encoder) writeMsg(ctx context.Context, c carrier, pixels int, at func(int) Point, msg []byte) (stats EncodeStats, err error) {
+
+	hops, err := e.hops(0, len(msg))
+	if err != nil {
+		return stats, err
+	}
+	r := e.reporter(len(msg) * e.byteBits())
+
+	if e.parallel(c, pixels) {
+		stats, err = e.writeParallel(ctx, c, pixels, at, msg, hops, &r)
+	} else {
+		stats, err = e.writeBand(ctx, c, 0, pixels, at, msg, hops, r.report)
+	}
+	if err != nil {
+		return stats, err
+	}
+
+	r.finish()
+
+	return stats, nil
+}
+
+/*
+writeBand writes the part of msg held by the pixels from lo up
+to but not including hi, calling report, if not nil, with the
+number of bits written so far before each pixel. hops are the
+bit planes of each byte of msg when bit hopping is enabled.
+*/
+func (e *Encoder) writeBand(ctx context.Context, c carrier, lo, hi int, at func(int) Point, msg, hops []byte, report func(int)) (stats EncodeStats, err error) {
+
+	var tmp [maxByteBits]bool
+	channels := e.activeChannels()
+	bases := e.msgBits(channels)
+	depth := e.bitDepth()
+	cb := e.byteBits()
+	n := lo * e.bitsPerPixel()
+
+	// A band may begin part way through a byte.
+	if n%cb != 0 && n < len(msg)*cb {
+		e.encodeByte(&tmp, msg[n/cb])
+	}
+
+	for i := lo; i < hi; i++ {
+
+		if (i-lo)%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return stats, err
+			}
+		}
+
+		if report != nil {
+			report(n)
+		}
+
+		p := at(i)
+		changed := false
+
+		for ci, ch := range channels {
+
+			old := c.sample(p.X, p.Y, ch)
+			v := old
+
+			for j := 0; j < depth && n < len(msg)*cb; j++ {
+
+				mod := n % cb
+
+				if mod == 0 {
+					e.encodeByte(&tmp, msg[n/cb])
+				}
+
+				plane := bases[ci] + j
+				if hops != nil {
+					plane = int(hops[n/cb])
+				}
+
+				if tmp[mod] { // set bit
+					v |= 1 << uint(plane)
+				} else { // clear bit
+					v &^= 1 << uint(plane)
+				}
+
+				n++
+			}
+
+			if v != old {
+				stats.BitsFlipped += bits.OnesCount16(v ^ old)
+				changed = true
+			}
+
+			c.setSample(p.X, p.Y, ch, v)
+		}
+
+		if e.parity && e.setParity(c, p) {
+			stats.BitsFlipped++
+			changed = true
+		}
+
+		stats.PixelsWritten++
+		if changed {
+			stats.PixelsChanged++
+		}
+	}
+
+	return stats, nil
+}
+
+func (e *Encoder) readMsg(ctx context.Context, c carrier, pixels int, at func(int) Point) (msg []byte, err error) {
+	return e.readMsgFrom(ctx, c, pixels, at, 0)
+}
+
+// readMsgFrom is like readMsg but for reading from the byte of
+// the message at offset from, which only matters when the bit
+// planes used depend on the position of each byte.
+func (e *Encoder) readMsgFrom(ctx context.Context, c carrier, pixels int, at func(int) Point, from int) (msg []byte, err error) {
+	return e.readMsgAt(ctx, c, pixels, at, from, 0)
+}
+
+// readMsgAt is like readMsgFrom but ignores the first skip bits
+// of the first pixel, for bytes that don't begin on a pixel.
+func (e *Encoder) readMsgAt(ctx context.Context, c carrier, pixels int, at func(int) Point, from, skip int) (msg []byte, err error) {
+
+	var tmp [maxByteBits]bool
+	var n int
+	channels := e.activeChannels()
+	bases := e.msgBits(channels)
+	depth := e.bitDepth()
+	cb := e.byteBits()
+	hops, err := e.hops(from, pixels*e.bitsPerPixel()/cb+1)
+	if err != nil {
+		return nil, err
+	}
+	r := e.reporter(pixels * e.bitsPerPixel())
+	msg = make([]byte, 0, pixels*e.bitsPerPixel()/cb)
+
+	for i := 0; i < pixels; i++ {
+
+		if i%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		r.report(n)
+
+		p := at(i)
+
+		for ci, ch := range channels {
+
+			v := c.sample(p.X, p.Y, ch)
+
+			for j := 0; j < depth; j++ {
+
+				if skip > 0 {
+					skip--
+					continue
+				}
+
+				mod := n % cb
+
+				plane := bases[ci] + j
+				if hops != nil {
+					plane = int(hops[n/cb])
+				}
+
+				if v&(1<<uint(plane)) == 0 {
+					tmp[mod] = false
+				} else {
+					tmp[mod] = true
+				}
+
+				if mod == cb-1 {
+					b, fixed := e.decodeByte(tmp[:cb])
+					if e.corrected != nil {
+						*e.corrected += fixed
+					}
+					msg = append(msg, b)
+				}
+
+				n++
+			}
+		}
+
+		if e.corrupt != nil {
+			e.verifyParity(c, p)
+		}
+	}
+
+	r.finish()
+
+	return msg, nil
+}
+
+/*
+Capacity returns how many bytes of message can be stored in
+the image at src from start until the bottom right corner of
+the image, given the encoder's current settings. Space taken by
+the magic marker, length header, checksum and the rest of the
+framing the settings add is left out, so a message of that
+length can always be encoded from start. It returns an error if
+start is outside the bounds of src.
+
+Because Encode's end return value must itself be a pixel
+within the image the very last pixel is never used to store
+message data.
+*/
+func (e *Encoder) Capacity(src string, start Point) (int, error) {
+
+	bounds, err := readBounds(src)
+	if err != nil {
+		return 0, err
+	}
+
+	return e.msgCapacity(bounds, start)
+}
+
+/*
+CapacityImage is like Capacity but takes an already decoded
+image.
+*/
+func (e *Encoder) CapacityImage(img image.Image, start Point) (int, error) {
+	if err := checkImage(img); err != nil {
+		return 0, err
+	}
+	return e.msgCapacity(img.Bounds(), start)
+}
+
+// msgCapacity is capacity less the framing of the longest
+// message that fits.
+func (e *Encoder) msgCapacity(bounds image.Rectangle, start Point) (int, error) {
+	n, err := e.capacity(bounds, start)
+	if err != nil {
+		return 0, err
+	}
+	return e.msgBytes(n), nil
+}
+
+// capacity returns how many bytes of payload, framing included,
+// fit from start.
+
+func (e *Encoder) capacity(bounds image.Rectangle, start Point) (int, error) {
+
+	pixels, n, err := e.remaining(bounds, start)
+	if err != nil {
+		return 0, err
+	}
+
+	e.logCapacity(start, pixels, n)
+	return n, nil
+}
+
+// remaining is capacity without logging, also returning how many
+// pixels are available from start.
+func (e *Encoder) remaining(bounds image.Rectangle, start Point) (pixels, n int, err error) {
+
+	if !inBounds(bounds, start) {
+		return 0, 0, ErrStartOutOfBounds
+	}
+
+	if set := e.pixels(nil, bounds, start); set != nil {
+		return set.len(), set.len() * e.bitsPerPixel() / e.byteBits(), nil
+	}
+
+	total := bounds.Dx() * bounds.Dy()
+	left := total - offsetFromMin(bounds, e.traversal, start) - e.formatPixels()
+	if left < 1 {
+		return 0, 0, nil
+	}
+
+	pixels = left - 1
+	if e.mirror {
+		pixels /= 2
+	}
+
+	return pixels, pixels * e.bitsPerPixel() / e.byteBits(), nil
+}
+
+// readBounds returns the bounds of the image at src without
+// decoding its pixels.
+func readBounds(src string) (image.Rectangle, error) {
+
+	src, err := filepath.Abs(src)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+
+	r, err := os.Open(src)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	defer r.Close()
+
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+
+	bounds := image.Rect(0, 0, cfg.Width, cfg.Height)
+	return bounds, checkBounds(bounds)
+}
+
+func readImage(src string) (image.Image, error) {
+
+	src, err := filepath.Abs(src)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// inBounds reports whether p lies in r, both being in the same
+// absolute coordinates.
+func inBounds(r image.Rectangle, p Point) bool {
+	if p.X < r.Min.X {
+		return false
+	}
+	if p.Y < r.Min.Y {
+		return false
+	}
+	if p.X >= r.Max.X {
+		return false
+	}
+	if p.Y >= r.Max.Y {
+		return false
+	}
+	return true
+}
+
+func bitsToByte(bits [8]bool, o BitOrder) (b byte) {
+
+	for i, bit := range bits {
+
+		// Bit position; e.g. 128, 64, 32, 16, etc for MSBFirst
+		if bit {
+			b |= 1 << o.shift(i)
+		}
+	}
+
+	return b
+}
+
+func byteToBits(bits *[8]bool, b byte, o BitOrder) {
+
+	for i := range bits {
+
+		// Bit position; e.g. 128, 64, 32, 16, etc for MSBFirst
+		bits[i] = b&(1<<o.shift(i)) != 0
+	}
 }
@@ -0,0 +1,380 @@
+package steg
+
+import (
+	"errors"
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestEncodeWidePixelsNeedsEnd(t *testing.T) {
+
+	rgb := []Option{WithChannels(ChannelRed, ChannelGreen, ChannelBlue), WithBitDepth(4)}
+
+	e, err := NewEncoder(rgb...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.EncodeImage(noisyNRGBA(16, 16), "hello", Point{}); err == nil {
+		t.Fatal("encoding 12 bits per pixel without a length header or terminator succeeded")
+	}
+
+	for _, opts := range [][]Option{
+		{WithLengthHeader()},
+		{WithLengthHeader(), WithChecksum()},
+		{WithNullTerminator()},
+	} {
+
+		e, err := NewEncoder(append(opts, rgb...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for n := 1; n <= 30; n++ {
+			img := noisyNRGBA(16, 16)
+			msg := strings.Repeat("x", n)
+			end, err := e.EncodeImage(img, msg, Point{})
+			if err != nil {
+				t.Fatalf("%s: len %d: %v", e, n, err)
+			}
+			got, err := e.DecodeImage(img, Point{}, end)
+			if err != nil {
+				t.Fatalf("%s: len %d: %v", e, n, err)
+			}
+			if got != msg {
+				t.Fatalf("%s: len %d: got %q, want %q", e, n, got, msg)
+			}
+		}
+	}
+}
+
+func TestDecodeStartBeforeEnd(t *testing.T) {
+
+	img := noisyNRGBA(10, 10)
+	offset := image.NewNRGBA(image.Rect(5, 5, 15, 15))
+	copy(offset.Pix, img.Pix)
+
+	tests := []struct {
+		name       string
+		img        image.Image
+		traversal  Traversal
+		start, end Point
+		ok         bool
+	}{
+		{"same row", img, TraversalRowMajor, Point{1, 2}, Point{9, 2}, true},
+		{"same column", img, TraversalRowMajor, Point{3, 1}, Point{3, 4}, true},
+		{"wrap around", img, TraversalRowMajor, Point{8, 1}, Point{6, 2}, true},
+		{"wrap to lower x", img, TraversalRowMajor, Point{7, 1}, Point{2, 3}, true},
+		{"whole image", img, TraversalRowMajor, Point{0, 0}, Point{9, 9}, true},
+		{"nonzero min", offset, TraversalRowMajor, Point{13, 5}, Point{6, 7}, true},
+		{"equal points", img, TraversalRowMajor, Point{5, 5}, Point{5, 5}, false},
+		{"end on earlier row", img, TraversalRowMajor, Point{5, 5}, Point{9, 4}, false},
+		{"end earlier in row", img, TraversalRowMajor, Point{5, 5}, Point{4, 5}, false},
+		{"end above in same column", img, TraversalRowMajor, Point{3, 4}, Point{3, 1}, false},
+		{"column-major same column", img, TraversalColumnMajor, Point{3, 1}, Point{3, 9}, true},
+		{"column-major wrap to lower y", img, TraversalColumnMajor, Point{5, 8}, Point{6, 6}, true},
+		{"column-major end in earlier column", img, TraversalColumnMajor, Point{5, 2}, Point{4, 8}, false},
+		{"column-major end earlier in column", img, TraversalColumnMajor, Point{5, 5}, Point{5, 4}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e Encoder
+			if err := e.SetTraversal(tt.traversal); err != nil {
+				t.Fatal(err)
+			}
+			_, err := e.DecodeImage(tt.img, tt.start, tt.end)
+			switch {
+			case tt.ok && err != nil:
+				t.Fatalf("got error %v, want none", err)
+			case !tt.ok && !errors.Is(err, ErrStartAfterEnd):
+				t.Fatalf("got error %v, want %v", err, ErrStartAfterEnd)
+			}
+		})
+	}
+}
+
+func BenchmarkEncodeImage(b *testing.B) {
+
+	var e Encoder
+	e.SetLengthHeader(true)
+
+	img := noisyNRGBA(3840, 2160)
+	msg := strings.Repeat("4K", 256<<10)
+
+	b.SetBytes(int64(len(msg)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := e.EncodeImage(img, msg, Point{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeImage(b *testing.B) {
+
+	var e Encoder
+	if err := e.SetChannels([]Channel{ChannelRed, ChannelGreen, ChannelBlue}); err != nil {
+		b.Fatal(err)
+	}
+
+	img := noisyNRGBA(2048, 2048)
+	msg := strings.Repeat("x", 1<<20)
+	end, err := e.EncodeImage(img, msg, Point{})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(msg)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		got, err := e.DecodeImage(img, Point{}, end)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(got) != len(msg) {
+			b.Fatalf("decoded %d bytes, want %d", len(got), len(msg))
+		}
+	}
+}
+
+func TestDecodeTooFewPixels(t *testing.T) {
+
+	img := noisyNRGBA(10, 10)
+
+	var e Encoder
+	if _, err := e.DecodeImage(img, Point{1, 0}, Point{8, 0}); err == nil {
+		t.Fatal("decoding 7 bits succeeded")
+	}
+	if _, err := e.DecodeImage(img, Point{1, 0}, Point{9, 0}); err != nil {
+		t.Fatalf("decoding 8 bits: %v", err)
+	}
+
+	if err := e.SetChannels([]Channel{ChannelRed, ChannelGreen, ChannelBlue}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.DecodeImage(img, Point{1, 0}, Point{3, 0}); err == nil {
+		t.Fatal("decoding 6 bits succeeded")
+	}
+	if _, err := e.DecodeImage(img, Point{1, 0}, Point{4, 0}); err != nil {
+		t.Fatalf("decoding 9 bits: %v", err)
+	}
+}
+
+func TestDecodeByteBoundary(t *testing.T) {
+
+	rgb := []Channel{ChannelRed, ChannelGreen, ChannelBlue}
+	rgba := []Channel{ChannelRed, ChannelGreen, ChannelBlue, ChannelAlpha}
+
+	// Above 8 bits per pixel the end point can't tell a partly used
+	// last pixel from a final byte, so encoding must be refused.
+	var wide Encoder
+	if err := wide.SetChannels(rgb); err != nil {
+		t.Fatal(err)
+	}
+	if err := wide.SetBitDepth(3); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wide.EncodeImage(noisyNRGBA(24, 24), "z", Point{}); err == nil {
+		t.Fatalf("%s: encoding without a length header or terminator succeeded", &wide)
+	}
+
+	for _, tt := range []struct {
+		channels   []Channel
+		depth      int
+		header     bool
+		terminator bool
+	}{
+		{nil, 1, false, false},
+		{nil, 3, false, false},
+		{rgb, 1, false, false},
+		{[]Channel{ChannelRed, ChannelGreen}, 3, false, false},
+		{rgb, 2, false, false},
+		{rgba, 2, false, false},
+		{rgb, 3, true, false},
+		{rgba, 4, true, false},
+		{rgb, 4, false, true},
+	} {
+
+		var e Encoder
+		if tt.channels != nil {
+			if err := e.SetChannels(tt.channels); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := e.SetBitDepth(tt.depth); err != nil {
+			t.Fatal(err)
+		}
+		e.SetLengthHeader(tt.header)
+		e.SetNullTerminator(tt.terminator)
+		bpp := e.bitsPerPixel()
+
+		for n := 1; n <= 17; n++ {
+
+			img := noisyNRGBA(24, 24)
+			msg := strings.Repeat("z", n)
+			start := Point{5, 2}
+
+			end, err := e.EncodeImage(img, msg, start)
+			if err != nil {
+				t.Fatalf("%+v: len %d: %v", tt, n, err)
+			}
+
+			if !tt.header && !tt.terminator {
+				pixels := offsetFromMin(img.Rect, e.traversal, end) - offsetFromMin(img.Rect, e.traversal, start)
+				if want := (n*8 + bpp - 1) / bpp; pixels != want {
+					t.Fatalf("%+v: len %d: wrote %d pixels, want %d", tt, n, pixels, want)
+				}
+			}
+
+			got, err := e.DecodeImage(img, start, end)
+			if err != nil {
+				t.Fatalf("%+v: len %d: %v", tt, n, err)
+			}
+			if got != msg {
+				t.Fatalf("%+v: len %d: got %q, want %q", tt, n, got, msg)
+			}
+		}
+	}
+}
+
+// Points given for a sub-image are in the parent's coordinates,
+// so encoding a sub-image from its Min writes the same pixels as
+// encoding a copy of it from (0, 0).
+func TestEncodeSubImage(t *testing.T) {
+
+	const msg = "cropped before embedding"
+
+	for _, traversal := range []Traversal{TraversalRowMajor, TraversalColumnMajor} {
+
+		var e Encoder
+		e.SetLengthHeader(true)
+		if err := e.SetTraversal(traversal); err != nil {
+			t.Fatal(err)
+		}
+
+		parent := image.NewRGBA(image.Rect(0, 0, 40, 40))
+		copy(parent.Pix, noisyNRGBA(40, 40).Pix)
+		orig := append([]byte(nil), parent.Pix...)
+
+		r := image.Rect(10, 12, 30, 32)
+		sub := parent.SubImage(r).(*image.RGBA)
+
+		copied := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+		for y := 0; y < r.Dy(); y++ {
+			for x := 0; x < r.Dx(); x++ {
+				copied.Set(x, y, sub.At(r.Min.X+x, r.Min.Y+y))
+			}
+		}
+
+		subCap, err := e.CapacityImage(sub, Point{r.Min.X, r.Min.Y})
+		if err != nil {
+			t.Fatalf("traversal %d: %v", traversal, err)
+		}
+		copiedCap, err := e.CapacityImage(copied, Point{})
+		if err != nil {
+			t.Fatalf("traversal %d: %v", traversal, err)
+		}
+		if subCap != copiedCap {
+			t.Fatalf("traversal %d: sub-image holds %d bytes, a copy of it %d", traversal, subCap, copiedCap)
+		}
+
+		if _, err = e.EncodeImage(sub, msg, Point{}); !errors.Is(err, ErrStartOutOfBounds) {
+			t.Fatalf("traversal %d: encoding from outside the sub-image: got error %v, want ErrStartOutOfBounds", traversal, err)
+		}
+
+		end, err := e.EncodeImage(sub, msg, Point{r.Min.X, r.Min.Y})
+		if err != nil {
+			t.Fatalf("traversal %d: %v", traversal, err)
+		}
+		if !inBounds(r, end) {
+			t.Fatalf("traversal %d: end %v is outside the sub-image %v", traversal, end, r)
+		}
+		copiedEnd, err := e.EncodeImage(copied, msg, Point{})
+		if err != nil {
+			t.Fatalf("traversal %d: %v", traversal, err)
+		}
+		if end.X-r.Min.X != copiedEnd.X || end.Y-r.Min.Y != copiedEnd.Y {
+			t.Fatalf("traversal %d: sub-image end %v doesn't match end %v of a copy", traversal, end, copiedEnd)
+		}
+
+		for y := 0; y < parent.Rect.Dy(); y++ {
+			for x := 0; x < parent.Rect.Dx(); x++ {
+				p := image.Pt(x, y)
+				i := parent.PixOffset(x, y)
+				switch {
+				case !p.In(r):
+					if string(parent.Pix[i:i+4]) != string(orig[i:i+4]) {
+						t.Fatalf("traversal %d: pixel %v outside the sub-image changed", traversal, p)
+					}
+				case parent.RGBAAt(x, y) != copied.RGBAAt(x-r.Min.X, y-r.Min.Y):
+					t.Fatalf("traversal %d: pixel %v differs from the same pixel of a copy", traversal, p)
+				}
+			}
+		}
+
+		got, err := e.DecodeImage(sub, Point{r.Min.X, r.Min.Y}, end)
+		if err != nil {
+			t.Fatalf("traversal %d: %v", traversal, err)
+		}
+		if got != msg {
+			t.Fatalf("traversal %d: got %q, want %q", traversal, got, msg)
+		}
+		if got, err = e.DecodeAutoImage(sub, Point{r.Min.X, r.Min.Y}); err != nil || got != msg {
+			t.Fatalf("traversal %d: DecodeAuto got %q, %v, want %q", traversal, got, err, msg)
+		}
+	}
+}
+
+func TestMsgBitWithDepth(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		depth int
+		bit   int
+		ok    bool
+	}{
+		{"top of low byte", 3, 5, true},
+		{"past bit 7", 3, 7, false},
+		{"past bit 7 by one", 3, 6, false},
+		{"depth 1 at bit 7", 1, 7, true},
+		{"bottom of high byte", 3, 8, true},
+		{"top of high byte", 3, 13, true},
+		{"past bit 15", 3, 14, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			var e Encoder
+			if err := e.SetBitDepth(tt.depth); err != nil {
+				t.Fatal(err)
+			}
+			if err := e.SetMsgBit(tt.bit); (err == nil) != tt.ok {
+				t.Fatalf("SetBitDepth(%d) then SetMsgBit(%d): got error %v, want ok %t", tt.depth, tt.bit, err, tt.ok)
+			}
+
+			var f Encoder
+			if err := f.SetMsgBit(tt.bit); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.SetBitDepth(tt.depth); (err == nil) != tt.ok {
+				t.Fatalf("SetMsgBit(%d) then SetBitDepth(%d): got error %v, want ok %t", tt.bit, tt.depth, err, tt.ok)
+			}
+
+			if _, err := NewEncoder(WithBitDepth(tt.depth), WithBit(tt.bit)); (err == nil) != tt.ok {
+				t.Fatalf("NewEncoder: got error %v, want ok %t", err, tt.ok)
+			}
+		})
+	}
+
+	var e Encoder
+	if err := e.SetBitDepth(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetChannelBits(map[Channel]int{ChannelRed: 0, ChannelGreen: 6}); err == nil {
+		t.Fatal("SetChannelBits accepted bit 6 with bit depth 3")
+	}
+}
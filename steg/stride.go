@@ -0,0 +1,151 @@
+package steg
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sort"
+)
+
+/*
+SetPixelStride makes the encoder only use pixels whose index, in
+traversal order from the top left pixel of the image, leaves a
+remainder of phase when divided by stride. Encoders with the
+same stride and different phases use separate pixels, so each
+can write its own message to the same image without either
+overwriting the other; with a stride of 2 one encoder can use
+the even pixels and another the odd. Messages must be decoded
+with the same stride and phase.
+
+Only one pixel in every stride is used so capacity is divided by
+stride. The pixel stride cannot be combined with SetSpread, the
+format header or a null terminator, and FindStart cannot be used
+with it. SetPixelStride returns an error if stride is less than
+1 or phase is not in the range 0 to stride-1. A stride of 1, the
+default, uses every pixel.
+*/
+func (e *Encoder) SetPixelStride(stride, phase int) error {
+	if stride < 1 {
+		return fmt.Errorf("pixel stride out of bounds: got %d, wanted 1 or more", stride)
+	}
+	if phase < 0 || phase >= stride {
+		return fmt.Errorf("pixel phase out of bounds: got %d, wanted 0-%d inclusive", phase, stride-1)
+	}
+	e.stride = stride
+	e.phase = phase
+	if stride == 1 {
+		e.stride = 0
+	}
+	return nil
+}
+
+func (e *Encoder) checkStride() error {
+	if e.stride == 0 {
+		return nil
+	}
+	if e.spread || e.formatHeader || e.terminator {
+		return errors.New("pixel stride cannot be combined with spread, the format header or a null terminator")
+	}
+	return nil
+}
+
+/*
+pixelSet lists the pixels from a start point that can hold
+message bits when density or a pixel stride rule some of them
+out, or transparent pixels are skipped. Pixels are identified by
+their offset from start in traversal order; the last pixel of
+the image is never included so that the point after a message is
+always in bounds. A nil *pixelSet means every pixel can be used.
+*/
+type pixelSet struct {
+	dense []int // offsets, when density or transparency rule pixels out
+
+	// Otherwise the offsets are first, first+stride and so on
+	// up to but not including avail.
+	first, stride, avail int
+}
+
+/*
+pixels returns the set of pixels of c from start that can hold
+message bits, or nil if all of them can. c may be nil, in which
+case density and transparency are not taken into account.
+*/
+func (e *Encoder) pixels(c carrier, bounds image.Rectangle, start Point) *pixelSet {
+
+	var dense []int
+	if c != nil {
+		dense = e.visibleOffsets(c, start, e.denseOffsets(c, start))
+	}
+	if dense == nil && e.stride == 0 {
+		return nil
+	}
+
+	first := offsetFromMin(bounds, e.traversal, start)
+
+	if e.stride == 0 {
+		return &pixelSet{dense: dense}
+	}
+
+	// offset of the first pixel from start with the right phase
+	skip := ((e.phase-first)%e.stride + e.stride) % e.stride
+
+	if dense != nil {
+		kept := dense[:0]
+		for _, o := range dense {
+			if o%e.stride == skip {
+				kept = append(kept, o)
+			}
+		}
+		return &pixelSet{dense: kept}
+	}
+
+	return &pixelSet{
+		first:  skip,
+		stride: e.stride,
+		avail:  bounds.Dx()*bounds.Dy() - first - 1,
+	}
+}
+
+// len returns the number of pixels in s.
+func (s *pixelSet) len() int {
+	if s.dense != nil {
+		return len(s.dense)
+	}
+	if s.avail <= s.first {
+		return 0
+	}
+	return (s.avail - s.first + s.stride - 1) / s.stride
+}
+
+// offset returns the offset from start of the i-th pixel of s.
+func (s *pixelSet) offset(i int) int {
+	if s.dense != nil {
+		return s.dense[i]
+	}
+	return s.first + i*s.stride
+}
+
+// before returns how many of the pixels of s have an offset
+// less than n.
+func (s *pixelSet) before(n int) int {
+	if s.dense != nil {
+		return sort.SearchInts(s.dense, n)
+	}
+	if n <= s.first {
+		return 0
+	}
+	if n > s.avail {
+		n = s.avail
+	}
+	return (n - s.first + s.stride - 1) / s.stride
+}
+
+// checkSetFits is checkFits for messages written to the pixels
+// of s.
+func checkSetFits(s *pixelSet, pixels int) error {
+	if pixels <= s.len() {
+		return nil
+	}
+	return fmt.Errorf("%w: %w: msg needs %d pixels but only %d usable pixels are available from start, %d short",
+		ErrMsgTooLarge, ErrEndOutOfBounds, pixels, s.len(), pixels-s.len())
+}
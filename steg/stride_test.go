@@ -0,0 +1,70 @@
+package steg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPixelStride(t *testing.T) {
+
+	img := noisyNRGBA(32, 32)
+	orig := copyNRGBA(img)
+
+	// Three encoders share the image, each from the same start.
+	msgs := []string{"first of three", "second of three", "third"}
+	ends := make([]Point, len(msgs))
+	encs := make([]*Encoder, len(msgs))
+
+	for phase, msg := range msgs {
+		e, err := NewEncoder(WithPixelStride(3, phase), WithLengthHeader(), WithChecksum())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ends[phase], err = e.EncodeImage(img, msg, Point{1, 2}); err != nil {
+			t.Fatalf("phase %d: %v", phase, err)
+		}
+		encs[phase] = e
+	}
+
+	for phase, e := range encs {
+		got, err := e.DecodeImage(img, Point{1, 2}, ends[phase])
+		if err != nil {
+			t.Fatalf("phase %d: %v", phase, err)
+		}
+		if got != msgs[phase] {
+			t.Fatalf("phase %d: got %q, want %q", phase, got, msgs[phase])
+		}
+	}
+
+	// Capacity is divided by the stride.
+	one, err := NewEncoder(WithPixelStride(1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = one.EncodeImage(copyNRGBA(orig), strings.Repeat("x", 100), Point{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = encs[0].EncodeImage(copyNRGBA(orig), strings.Repeat("x", 100), Point{}); err == nil {
+		t.Fatal("a stride of 3 held as much as a stride of 1")
+	}
+}
+
+func TestPixelStrideSettings(t *testing.T) {
+
+	var e Encoder
+	for _, tt := range [][2]int{{0, 0}, {-1, 0}, {2, 2}, {3, -1}} {
+		if err := e.SetPixelStride(tt[0], tt[1]); err == nil {
+			t.Errorf("SetPixelStride accepted a stride of %d and phase of %d", tt[0], tt[1])
+		}
+	}
+
+	for name, opts := range map[string][]Option{
+		"spread":            {WithLengthHeader(), WithSpread()},
+		"the format header": {WithFormatHeader(true)},
+		"a null terminator": {WithNullTerminator()},
+	} {
+		if _, err := NewEncoder(append(opts, WithPixelStride(2, 1))...); err == nil {
+			t.Errorf("pixel stride was accepted with %s", name)
+		}
+	}
+}
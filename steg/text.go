@@ -0,0 +1,130 @@
+package steg
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+/*
+DecodeAny is like Decode but falls back to reading the message
+from a PNG text chunk with the keyword textKey when decoding it
+from the pixels of src fails, for sources where some messages are
+hidden in the image data and others stored as metadata. The text
+may be held in a tEXt, zTXt or iTXt chunk; tEXt and zTXt text is
+converted from Latin-1 to UTF-8. The first chunk with a matching
+keyword is used.
+
+The fallback is only tried when Decode returns an error, so a
+magic marker or checksum (see SetMagic and SetChecksum) is needed
+for an image without a message in its pixels to be recognised as
+such. If there is no chunk with the keyword the error from Decode
+is returned.
+*/
+func (e *Encoder) DecodeAny(src string, start, end Point, textKey string) (string, error) {
+
+	src, err := filepath.Abs(src)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return "", err
+	}
+
+	msg, err := e.DecodeFromBytes(data, start, end)
+	if err == nil {
+		return msg, nil
+	}
+
+	if text, ok := pngText(data, textKey); ok {
+		return text, nil
+	}
+
+	return "", err
+}
+
+/*
+pngText returns the text of the first tEXt, zTXt or iTXt chunk
+in the PNG data with the given keyword. It reports false if
+there is no such chunk or it can't be decoded.
+*/
+func pngText(data []byte, key string) (string, bool) {
+
+	for _, chunk := range splitPNGChunks(data) {
+
+		body := chunk[8 : len(chunk)-4]
+		k, rest, ok := bytes.Cut(body, []byte{0})
+		if !ok || string(k) != key {
+			continue
+		}
+
+		switch string(chunk[4:8]) {
+
+		case "tEXt":
+			return latin1(rest), true
+
+		case "zTXt":
+			// A compression method byte, always 0 for zlib,
+			// precedes the text.
+			if len(rest) < 1 || rest[0] != 0 {
+				return "", false
+			}
+			b, err := inflate(rest[1:])
+			if err != nil {
+				return "", false
+			}
+			return latin1(b), true
+
+		case "iTXt":
+			// A compression flag and method are followed by a
+			// language tag and translated keyword, each ending
+			// with a NUL.
+			if len(rest) < 2 {
+				return "", false
+			}
+			compressed := rest[0] == 1
+			_, rest, ok = bytes.Cut(rest[2:], []byte{0})
+			if !ok {
+				return "", false
+			}
+			_, rest, ok = bytes.Cut(rest, []byte{0})
+			if !ok {
+				return "", false
+			}
+			if compressed {
+				b, err := inflate(rest)
+				if err != nil {
+					return "", false
+				}
+				rest = b
+			}
+			return string(rest), true
+		}
+	}
+
+	return "", false
+}
+
+func inflate(b []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// latin1 converts ISO 8859-1 text, as tEXt and zTXt chunks hold,
+// to UTF-8.
+func latin1(b []byte) string {
+	s := make([]byte, 0, len(b))
+	for _, c := range b {
+		s = utf8.AppendRune(s, rune(c))
+	}
+	return string(s)
+}
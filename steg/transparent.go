@@ -0,0 +1,100 @@
+package steg
+
+import "errors"
+
+/*
+SetSkipTransparent makes the encoder skip fully transparent
+pixels, those with an alpha of zero, and only write to pixels
+that can be seen. The colour of a transparent pixel is never
+shown so writing to it only wastes capacity, and in
+premultiplied images such as *image.RGBA it would leave the
+pixel with an invalid colour. Messages must be decoded with the
+same setting. Only images with an alpha channel have transparent
+pixels; for others, including paletted images, every pixel is
+used as before.
+
+The alpha channel can't be written to while transparent pixels
+are skipped, since that could change which pixels are
+transparent. The alpha channel is only ever written to when it
+is given to SetChannel, SetChannels or SetChannelBits, so by
+default transparency is already left intact.
+
+How many pixels are transparent depends on the image, so as with
+SetDensity the capacity reported by Capacity and CapacityInfo is
+an upper bound when transparent pixels are skipped, and
+PointAfter and RegionFor don't take them into account. Skipping
+transparent pixels cannot be combined with SetSpread, SetChroma,
+the format header or a null terminator, and FindStart cannot be
+used with it. It is disabled by default.
+*/
+func (e *Encoder) SetSkipTransparent(enabled bool) {
+	e.skipTransparent = enabled
+}
+
+func (e *Encoder) checkSkipTransparent() error {
+	if !e.skipTransparent {
+		return nil
+	}
+	if e.spread || e.chroma || e.formatHeader || e.terminator {
+		return errors.New("skipping transparent pixels cannot be combined with spread, chroma, the format header or a null terminator")
+	}
+	for _, c := range e.activeChannels() {
+		if c == ChannelAlpha {
+			return errors.New("cannot skip transparent pixels while writing to the alpha channel")
+		}
+	}
+	return nil
+}
+
+/*
+visibleOffsets returns the offsets from start, in traversal
+order, of the pixels of c that aren't fully transparent, or nil
+if transparent pixels aren't skipped. When dense is non-nil only
+the offsets in it are considered. As with denseOffsets the last
+pixel of c is never included.
+*/
+func (e *Encoder) visibleOffsets(c carrier, start Point, dense []int) []int {
+
+	if !e.skipTransparent {
+		return dense
+	}
+
+	bounds := c.bounds()
+	first := offsetFromMin(bounds, e.traversal, start)
+
+	visible := func(o int) bool {
+		p := pointAt(bounds, e.traversal, first+o)
+		return !transparent(c, p.X, p.Y)
+	}
+
+	if dense != nil {
+		kept := dense[:0]
+		for _, o := range dense {
+			if visible(o) {
+				kept = append(kept, o)
+			}
+		}
+		return kept
+	}
+
+	n := bounds.Dx()*bounds.Dy() - first - 1
+	kept := make([]int, 0, max(n, 0))
+	for o := 0; o < n; o++ {
+		if visible(o) {
+			kept = append(kept, o)
+		}
+	}
+	return kept
+}
+
+// transparent reports whether the pixel of c at x, y has an
+// alpha of zero.
+func transparent(c carrier, x, y int) bool {
+	switch c := c.(type) {
+	case regionCarrier:
+		return transparent(c.carrier, x, y)
+	case rgbaCarrier, rgba64Carrier:
+		return c.sample(x, y, ChannelAlpha) == 0
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package steg
+
+import "testing"
+
+func TestSkipTransparent(t *testing.T) {
+
+	e, err := NewEncoder(WithSkipTransparent(), WithChannels(ChannelRed, ChannelGreen), WithLengthHeader(), WithChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every third pixel is fully transparent.
+	orig := noisyNRGBA(32, 32)
+	for i := 0; i < 32*32; i += 3 {
+		orig.Pix[4*i+3] = 0
+	}
+
+	const msg = "only where it can be seen"
+	img := copyNRGBA(orig)
+	end, err := e.EncodeImage(img, msg, Point{2, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed := changedPixels(orig, img)
+	if len(changed) == 0 {
+		t.Fatal("no pixels changed")
+	}
+	for _, i := range changed {
+		if i%3 == 0 {
+			t.Fatalf("transparent pixel %d changed", i)
+		}
+	}
+
+	got, err := e.DecodeImage(img, Point{2, 0}, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != msg {
+		t.Fatalf("got %q, want %q", got, msg)
+	}
+
+	// Without transparency every pixel is used as before.
+	opaque := noisyNRGBA(32, 32)
+	plain, err := NewEncoder(WithChannels(ChannelRed, ChannelGreen), WithLengthHeader(), WithChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := plain.EncodeImage(copyNRGBA(opaque), msg, Point{2, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if end, err = e.EncodeImage(opaque, msg, Point{2, 0}); err != nil || end != want {
+		t.Fatalf("opaque image: got end %v, %v, want %v", end, err, want)
+	}
+}
+
+func TestSkipTransparentSettings(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"spread":                {WithLengthHeader(), WithSpread()},
+		"chroma":                {WithChroma()},
+		"the format header":     {WithFormatHeader(true)},
+		"a null terminator":     {WithNullTerminator()},
+		"the alpha channel":     {WithChannels(ChannelRed, ChannelAlpha)},
+		"alpha in channel bits": {WithChannelBits(map[Channel]int{ChannelAlpha: 0})},
+	} {
+		if _, err := NewEncoder(append(opts, WithSkipTransparent())...); err == nil {
+			t.Errorf("skipping transparent pixels was accepted with %s", name)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package steg
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math/rand"
+)
+
+/*
+Traversal specifies the order in which the pixels of an image
+are visited when writing and reading messages.
+*/
+type Traversal int
+
+const (
+	// TraversalRowMajor visits pixels left to right, moving
+	// down to the start of the next row at the end of each row.
+	TraversalRowMajor Traversal = iota
+
+	// TraversalColumnMajor visits pixels top to bottom, moving
+	// right to the top of the next column at the end of each
+	// column.
+	TraversalColumnMajor
+
+	// TraversalHilbert visits pixels along a Hilbert curve
+	// covering the image, so consecutive pixels stay close to
+	// each other without following its rows or columns. For
+	// images whose sides aren't equal powers of two a
+	// generalised curve is used, which may take a diagonal
+	// step where both sides are odd.
+	TraversalHilbert
+)
+
+func (t Traversal) valid() bool {
+	return t >= TraversalRowMajor && t <= TraversalHilbert
+}
+
+/*
+SetTraversal specifies the order in which pixels are visited
+from the start point. Messages must be decoded using the same
+traversal they were encoded with. If t is not a valid
+Traversal SetTraversal returns an error. By default pixels are
+visited in row-major order.
+*/
+func (e *Encoder) SetTraversal(t Traversal) error {
+	if !t.valid() {
+		return fmt.Errorf("invalid traversal: got %d", t)
+	}
+	e.traversal = t
+	return nil
+}
+
+/*
+SetScatterSeed makes the encoder write the message to pixels
+chosen in a pseudo-random order instead of consecutively. The
+order is a permutation of the pixels from start to end, so no
+pixel is used twice and none outside of that span are used;
+it is derived from seed and the same seed must be used to
+decode the message. The magic marker and length header, when
+enabled, are not scattered so that DecodeAuto can still find
+them at start.
+*/
+func (e *Encoder) SetScatterSeed(seed int64) {
+	e.scatter = true
+	e.scatterSeed = seed
+}
+
+/*
+SetSpread specifies whether the message is spread evenly over
+the pixels from start to the bottom right corner of the image
+rather than being written to consecutive pixels. With spread
+enabled one pixel is used every
+
+	(remainingPixels - headerPixels) / bodyPixels
+
+pixels, so short messages touch pixels far apart from each
+other. Because that stride depends on the length of the
+message the length header must also be enabled (see
+SetLengthHeader); the header itself is written to consecutive
+pixels from start. Spreading cannot be combined with
+SetScatterSeed.
+
+Encode returns an error if the message needs more pixels than
+are available. For Decode the end point must be in bounds but
+is otherwise ignored.
+*/
+func (e *Encoder) SetSpread(enabled bool) {
+	e.spread = enabled
+}
+
+func (e *Encoder) checkLayout() error {
+	if e.spread && !e.lengthHeader {
+		return errors.New("spread requires the length header to be enabled")
+	}
+	if e.spread && e.scatter {
+		return errors.New("spread and scatter cannot be combined")
+	}
+	return nil
+}
+
+/*
+walk returns a function giving the pixel at each position of
+a message that takes up the given number of pixels from start,
+along with the point after the message. If set is not nil only
+its pixels are used.
+*/
+func (e *Encoder) walk(bounds image.Rectangle, start Point, set *pixelSet, hdrSize, pixels int) (at func(int) Point, end Point, err error) {
+
+	first := offsetFromMin(bounds, e.traversal, start)
+
+	linear := func(i int) Point {
+		return pointAt(bounds, e.traversal, first+i)
+	}
+	after := linear(pixels)
+
+	if set != nil {
+		linear = func(i int) Point {
+			return pointAt(bounds, e.traversal, first+set.offset(i))
+		}
+		if pixels > 0 {
+			after = pointAt(bounds, e.traversal, first+set.offset(pixels-1)+1)
+		}
+	}
+
+	hdr := e.headerPixels(hdrSize)
+
+	switch {
+
+	case e.spread && hdr < pixels:
+
+		// Exclude the last pixel as end must lie in the image.
+		avail := bounds.Dx()*bounds.Dy() - first - 1
+		body := pixels - hdr
+		stride := (avail - hdr) / body
+
+		if stride < 1 {
+			return nil, end, errors.New("msg is too large to spread over the image")
+		}
+
+		at = func(i int) Point {
+			if i < hdr {
+				return linear(i)
+			}
+			return linear(hdr + (i-hdr)*stride)
+		}
+		end = linear(hdr + (body-1)*stride + 1)
+
+		return at, end, nil
+
+	case e.scatter && hdr < pixels:
+
+		perm := rand.New(rand.NewSource(e.scatterSeed)).Perm(pixels - hdr)
+
+		at = func(i int) Point {
+			if i < hdr {
+				return linear(i)
+			}
+			return linear(hdr + perm[i-hdr])
+		}
+	}
+
+	if at == nil {
+		at = linear
+	}
+
+	return at, after, nil
+}
+
+/*
+PointAfter returns the end point Encode returns when writing a
+message of byteLen bytes from start to an image with the given
+bounds, so that end can be recomputed from the length of a
+message, for instance when it is moved to another image of the
+same size. The encoder's settings are taken into account; when
+compression is enabled the size of the message once compressed
+isn't known so the point is for the largest size it could be.
+The point returned lies outside bounds if the message doesn't
+fit, and is start itself if bounds is empty.
+*/
+func (e *Encoder) PointAfter(start Point, byteLen int, bounds image.Rectangle) Point {
+
+	if checkBounds(bounds) != nil {
+		return start
+	}
+
+	size, hdr := e.framedSize(byteLen)
+	pixels := e.payloadPixels(size, hdr)
+	start = e.bodyStart(bounds, start)
+	set := e.pixels(nil, bounds, start)
+
+	_, end, err := e.walk(bounds, start, set, hdr, pixels)
+	if err != nil {
+		first := offsetFromMin(bounds, e.traversal, start)
+		return pointAt(bounds, e.traversal, first+pixels)
+	}
+
+	return end
+}
+
+/*
+offsetFromMin returns the number of pixels visited by t before
+reaching p when starting from the first pixel of r. Points
+outside r are given row-major offsets with TraversalHilbert, so
+that those past the end of the curve lie below r.
+*/
+func offsetFromMin(r image.Rectangle, t Traversal, p Point) int {
+	if t == TraversalHilbert && inBounds(r, p) {
+		return hilbertOffset(r, p)
+	}
+	if t == TraversalColumnMajor {
+		return (p.X-r.Min.X)*r.Dy() + (p.Y - r.Min.Y)
+	}
+	return (p.Y-r.Min.Y)*r.Dx() + (p.X - r.Min.X)
+}
+
+// pointAt is the inverse of offsetFromMin.
+func pointAt(r image.Rectangle, t Traversal, offset int) Point {
+	if t == TraversalHilbert && offset >= 0 && offset < r.Dx()*r.Dy() {
+		return hilbertPoint(r, offset)
+	}
+	if t == TraversalColumnMajor {
+		return Point{r.Min.X + offset/r.Dy(), r.Min.Y + offset%r.Dy()}
+	}
+	return Point{r.Min.X + offset%r.Dx(), r.Min.Y + offset/r.Dx()}
+}
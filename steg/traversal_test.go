@@ -0,0 +1,309 @@
+package steg
+
+import (
+	"errors"
+	"image"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEncodeNearRightEdge(t *testing.T) {
+
+	const msg = "wraps onto the next row"
+
+	for _, traversal := range []Traversal{TraversalRowMajor, TraversalColumnMajor} {
+		for _, r := range []image.Rectangle{
+			image.Rect(0, 0, 24, 20),
+			image.Rect(5, 3, 29, 23),
+		} {
+
+			// Column-major messages need several columns, so they
+			// start further from the right edge but still wrap
+			// from the bottom of one column to the top of the next.
+			starts := []Point{
+				{r.Max.X - 1, r.Min.Y},
+				{r.Max.X - 3, r.Min.Y + 2},
+				{r.Max.X - 1, r.Max.Y - 12},
+			}
+			if traversal == TraversalColumnMajor {
+				starts = []Point{
+					{r.Max.X - 16, r.Max.Y - 1},
+					{r.Max.X - 12, r.Min.Y + 3},
+					{r.Min.X + 1, r.Max.Y - 2},
+				}
+			}
+
+			for _, start := range starts {
+
+				var e Encoder
+				if err := e.SetTraversal(traversal); err != nil {
+					t.Fatal(err)
+				}
+
+				img := image.NewNRGBA(r)
+				copy(img.Pix, noisyNRGBA(r.Dx(), r.Dy()).Pix)
+				orig := image.NewNRGBA(r)
+				copy(orig.Pix, img.Pix)
+
+				end, err := e.EncodeImage(img, msg, start)
+				if err != nil {
+					t.Fatalf("traversal %d %v from %v: %v", traversal, r, start, err)
+				}
+
+				first := offsetFromMin(r, traversal, start)
+				if got, want := offsetFromMin(r, traversal, end)-first, len(msg)*8; got != want {
+					t.Fatalf("traversal %d %v from %v: end %v is %d pixels on, want %d", traversal, r, start, end, got, want)
+				}
+
+				got, err := e.DecodeImage(img, start, end)
+				if err != nil {
+					t.Fatalf("traversal %d %v from %v: %v", traversal, r, start, err)
+				}
+				if got != msg {
+					t.Fatalf("traversal %d %v from %v: got %q, want %q", traversal, r, start, got, msg)
+				}
+
+				// Nothing outside the pixels from start to end changes.
+				for i := 0; i < r.Dx()*r.Dy(); i++ {
+					if i >= first && i < first+len(msg)*8 {
+						continue
+					}
+					p := pointAt(r, traversal, i)
+					if img.NRGBAAt(p.X, p.Y) != orig.NRGBAAt(p.X, p.Y) {
+						t.Fatalf("traversal %d %v from %v: pixel %v outside the message changed", traversal, r, start, p)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestHilbertCurve(t *testing.T) {
+
+	for _, r := range []image.Rectangle{
+		image.Rect(0, 0, 16, 16),
+		image.Rect(0, 0, 32, 8),
+		image.Rect(4, 7, 14, 31),
+		image.Rect(0, 0, 23, 17),
+		image.Rect(0, 0, 1, 9),
+		image.Rect(-3, -3, 6, 2),
+	} {
+
+		seen := make(map[Point]bool)
+		var prev Point
+		diagonal := 0
+
+		for i := 0; i < r.Dx()*r.Dy(); i++ {
+
+			p := pointAt(r, TraversalHilbert, i)
+			if !inBounds(r, p) || seen[p] {
+				t.Fatalf("%v: offset %d gives %v, which is outside r or repeated", r, i, p)
+			}
+			seen[p] = true
+
+			if got := offsetFromMin(r, TraversalHilbert, p); got != i {
+				t.Fatalf("%v: %v is at offset %d, want %d", r, p, got, i)
+			}
+
+			// The curve steps to a neighbouring pixel, diagonally
+			// at most once when a side is odd.
+			if i > 0 {
+				dx, dy := abs(p.X-prev.X), abs(p.Y-prev.Y)
+				switch {
+				case dx+dy == 1:
+				case dx == 1 && dy == 1:
+					diagonal++
+				default:
+					t.Fatalf("%v: step from %v to %v at offset %d", r, prev, p, i)
+				}
+			}
+			prev = p
+		}
+
+		if r.Dx()%2 == 0 && r.Dy()%2 == 0 && diagonal != 0 || diagonal > 1 {
+			t.Fatalf("%v: curve steps diagonally %d times", r, diagonal)
+		}
+	}
+}
+
+func TestHilbertRoundTrip(t *testing.T) {
+
+	const msg = "along the curve"
+
+	e, err := NewEncoder(WithTraversal(TraversalHilbert), WithLengthHeader(), WithChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range []image.Rectangle{image.Rect(0, 0, 16, 16), image.Rect(3, 5, 26, 22)} {
+
+		img := image.NewNRGBA(r)
+		copy(img.Pix, noisyNRGBA(r.Dx(), r.Dy()).Pix)
+
+		start := Point{r.Min.X + 2, r.Min.Y + 3}
+		end, err := e.EncodeImage(img, msg, start)
+		if err != nil {
+			t.Fatalf("%v: %v", r, err)
+		}
+
+		got, err := e.DecodeImage(img, start, end)
+		if err != nil {
+			t.Fatalf("%v: %v", r, err)
+		}
+		if got != msg {
+			t.Fatalf("%v: got %q, want %q", r, got, msg)
+		}
+		if got, err = e.DecodeAutoImage(img, start); err != nil || got != msg {
+			t.Fatalf("%v: DecodeAuto gave %q, %v", r, got, err)
+		}
+	}
+
+	// Without a size to grow the image to, only the shortfall is
+	// reported.
+	_, err = e.EncodeImage(noisyNRGBA(8, 8), msg, Point{})
+	if !errors.Is(err, ErrMsgTooLarge) || strings.Contains(err.Error(), "would need to be") {
+		t.Fatalf("got %v, want ErrMsgTooLarge giving only the shortfall", err)
+	}
+}
+
+// changedPixels returns the offsets of the pixels that differ
+// between a and b, in row-major order.
+func changedPixels(a, b *image.NRGBA) []int {
+	var changed []int
+	for i := 0; i < len(a.Pix); i += 4 {
+		if a.Pix[i] != b.Pix[i] || a.Pix[i+1] != b.Pix[i+1] || a.Pix[i+2] != b.Pix[i+2] || a.Pix[i+3] != b.Pix[i+3] {
+			changed = append(changed, i/4)
+		}
+	}
+	return changed
+}
+
+func TestScatter(t *testing.T) {
+
+	const msg = "scattered over the span of the message"
+	start := Point{5, 2}
+
+	var prev []int
+	for _, seed := range []int64{1, 2} {
+
+		e, err := NewEncoder(WithScatterSeed(seed), WithLengthHeader(), WithChecksum())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		orig := noisyNRGBA(32, 32)
+		img := copyNRGBA(orig)
+		end, err := e.EncodeImage(img, msg, start)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Scattering reorders the pixels from start to end but
+		// doesn't change how many there are.
+		first := offsetFromMin(img.Rect, TraversalRowMajor, start)
+		last := offsetFromMin(img.Rect, TraversalRowMajor, end)
+		size, _ := e.framedSize(len(msg))
+		if last-first != size*8 {
+			t.Fatalf("seed %d: message takes up %d pixels, want %d", seed, last-first, size*8)
+		}
+		changed := changedPixels(orig, img)
+		for _, i := range changed {
+			if i < first || i >= last {
+				t.Fatalf("seed %d: pixel %d outside the message changed", seed, i)
+			}
+		}
+		if prev != nil && slices.Equal(prev, changed) {
+			t.Fatalf("seeds 1 and 2 changed the same pixels")
+		}
+		prev = changed
+
+		got, err := e.DecodeImage(img, start, end)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		if got != msg {
+			t.Fatalf("seed %d: got %q, want %q", seed, got, msg)
+		}
+
+		// The header isn't scattered so DecodeAuto finds it.
+		if got, err = e.DecodeAutoImage(img, start); err != nil || got != msg {
+			t.Fatalf("seed %d: DecodeAuto gave %q, %v", seed, got, err)
+		}
+
+		other, err := NewEncoder(WithScatterSeed(seed+10), WithLengthHeader(), WithChecksum())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = other.DecodeImage(img, start, end); err == nil {
+			t.Fatalf("seed %d: decoded with seed %d", seed, seed+10)
+		}
+	}
+}
+
+func TestSpread(t *testing.T) {
+
+	e, err := NewEncoder(WithSpread(), WithLengthHeader(), WithChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const msg = "short"
+	start := Point{3, 1}
+
+	orig := noisyNRGBA(40, 40)
+	img := copyNRGBA(orig)
+	end, err := e.EncodeImage(img, msg, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The header is written to consecutive pixels from start and
+	// the body is spread over the rest of the image.
+	first := offsetFromMin(img.Rect, TraversalRowMajor, start)
+	size, hdr := e.framedSize(len(msg))
+	avail := 40*40 - first - 1
+	stride := (avail - hdr*8) / ((size - hdr) * 8)
+	if stride < 2 {
+		t.Fatalf("stride is %d, want a message short enough to spread", stride)
+	}
+	for _, i := range changedPixels(orig, img) {
+		if j := i - first - hdr*8; i < first || j >= 0 && j%stride != 0 {
+			t.Fatalf("pixel %d changed, which is neither in the header nor on the stride of %d", i, stride)
+		}
+	}
+	if last := offsetFromMin(img.Rect, TraversalRowMajor, end); last < 40*40*9/10 {
+		t.Fatalf("message ends at pixel %d, want it spread to the end of the image", last)
+	}
+
+	got, err := e.DecodeImage(img, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != msg {
+		t.Fatalf("got %q, want %q", got, msg)
+	}
+
+	// End is ignored beyond being in bounds.
+	if got, err = e.DecodeImage(img, start, Point{39, 39}); err != nil || got != msg {
+		t.Fatalf("decoding to the last pixel gave %q, %v", got, err)
+	}
+
+	if _, err = e.EncodeImage(noisyNRGBA(8, 8), strings.Repeat("x", 20), Point{}); !errors.Is(err, ErrMsgTooLarge) {
+		t.Fatalf("got %v, want ErrMsgTooLarge", err)
+	}
+}
+
+func TestSpreadSettings(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"without the length header": {WithSpread()},
+		"with scatter":              {WithSpread(), WithLengthHeader(), WithScatterSeed(1)},
+		"with density":              {WithSpread(), WithLengthHeader(), WithDensity(1, 3)},
+		"with a pixel stride":       {WithSpread(), WithLengthHeader(), WithPixelStride(2, 0)},
+		"skipping transparent":      {WithSpread(), WithLengthHeader(), WithSkipTransparent()},
+	} {
+		if _, err := NewEncoder(opts...); err == nil {
+			t.Errorf("spread was accepted %s", name)
+		}
+	}
+}
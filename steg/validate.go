@@ -0,0 +1,50 @@
+package steg
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+/*
+Validate runs the same checks as Encode would for msg and the
+image at src, returning the end point Encode would return, but
+doesn't change any pixels or write any output. msg is still
+compressed and encrypted when those are enabled so that the
+size checked is exact, which means Validate takes as long as
+key derivation when a passphrase is set.
+*/
+func (e *Encoder) Validate(src, msg string, start Point) (end Point, err error) {
+
+	p, err := readImage(src)
+	if err != nil {
+		return end, err
+	}
+
+	img, ok := fromJPEG(p).(draw.Image)
+	if !ok {
+		return end, fmt.Errorf("%w %T", ErrUnsupportedImage, p)
+	}
+
+	if e.usesChannel(ChannelAlpha) {
+		img = nonPremultiplied(img)
+	}
+
+	return e.ValidateImage(img, msg, start)
+}
+
+// ValidateImage is like Validate but runs the checks
+// EncodeImage would for img.
+func (e *Encoder) ValidateImage(img image.Image, msg string, start Point) (end Point, err error) {
+
+	c, err := e.carrierFor(img)
+	if err != nil {
+		return end, err
+	}
+	if err = alphaCheck(img, e.activeChannels()); err != nil {
+		return end, err
+	}
+
+	p, err := e.place(c, []byte(msg), start)
+	return p.end, err
+}
@@ -0,0 +1,68 @@
+package steg
+
+import (
+	"context"
+	"image"
+)
+
+/*
+DecodeResult describes how far a message returned by
+DecodeVerified can be trusted. Valid reports whether a message
+was decoded at all; when it is false DecodeVerified also returns
+the error that stopped it, such as ErrNoMessage or
+ErrChecksumMismatch. Checksum reports whether the message's
+checksum was present and matched (see SetChecksum), which makes
+it very unlikely the message was changed after encoding.
+
+Corrected is the number of bits fixed by error correction (see
+SetErrorCorrection) and Corrupted the number of pixels failing
+their parity check (see SetParity), both counted over everything
+read from the image, including any magic marker, headers and,
+with SetMirror, both copies. Either being above zero means the
+image was altered after encoding even if the message survived.
+*/
+type DecodeResult struct {
+	Valid     bool `json:"valid"`
+	Checksum  bool `json:"checksum"`
+	Corrected int  `json:"corrected"`
+	Corrupted int  `json:"corrupted"`
+}
+
+/*
+DecodeVerified is like Decode but also returns a DecodeResult
+summarising the integrity checks made while decoding, for
+callers that need to know how much to trust msg rather than only
+whether decoding succeeded. result is filled in as far as
+decoding got even when err is not nil.
+*/
+func (e *Encoder) DecodeVerified(src string, start, end Point) (msg string, result DecodeResult, err error) {
+
+	img, err := readImage(src)
+	if err != nil {
+		return msg, result, err
+	}
+
+	return e.DecodeImageVerified(img, start, end)
+}
+
+// DecodeImageVerified is like DecodeVerified but reads msg
+// directly from img.
+func (e *Encoder) DecodeImageVerified(img image.Image, start, end Point) (msg string, result DecodeResult, err error) {
+
+	d := *e
+	d.corrected = &result.Corrected
+	d.verified = &result.Checksum
+	if e.parity {
+		d.corrupt = make(map[Point]bool)
+	}
+
+	b, err := d.decodeImage(context.Background(), img, start, end)
+	result.Corrupted = len(d.corrupt)
+	if err != nil {
+		result.Checksum = false
+		return msg, result, err
+	}
+
+	result.Valid = true
+	return string(b), result, nil
+}
@@ -0,0 +1,97 @@
+package steg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecodeVerified(t *testing.T) {
+
+	start := Point{2, 1}
+
+	e, err := NewEncoder(WithParity(), WithErrorCorrection(ECCHamming74), WithScatterSeed(3), WithLengthHeader(), WithChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, msg := range []string{"hi", "verified and clean"} {
+
+		img := noisyNRGBA(64, 64)
+		end, err := e.EncodeImage(img, msg, start)
+		if err != nil {
+			t.Fatalf("%q: %v", msg, err)
+		}
+
+		got, result, err := e.DecodeImageVerified(img, start, end)
+		if err != nil {
+			t.Fatalf("%q: %v", msg, err)
+		}
+		if got != msg {
+			t.Fatalf("got %q, want %q", got, msg)
+		}
+		if want := (DecodeResult{Valid: true, Checksum: true}); result != want {
+			t.Fatalf("%q: clean image gave %+v, want %+v", msg, result, want)
+		}
+
+		// A changed message bit is corrected and fails parity.
+		img.Pix[img.PixOffset(start.X, start.Y)] ^= 1
+
+		got, result, err = e.DecodeImageVerified(img, start, end)
+		if err != nil {
+			t.Fatalf("%q: %v", msg, err)
+		}
+		if got != msg {
+			t.Fatalf("got %q, want %q", got, msg)
+		}
+		if want := (DecodeResult{Valid: true, Checksum: true, Corrected: 1, Corrupted: 1}); result != want {
+			t.Fatalf("%q: one changed bit gave %+v, want %+v", msg, result, want)
+		}
+	}
+}
+
+func TestDecodeVerifiedNoChecksum(t *testing.T) {
+
+	e, err := NewEncoder(WithLengthHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := noisyNRGBA(32, 32)
+	end, err := e.EncodeImage(img, "unchecked", Point{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, result, err := e.DecodeImageVerified(img, Point{}, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (DecodeResult{Valid: true}); result != want {
+		t.Fatalf("got %+v, want %+v", result, want)
+	}
+}
+
+func TestDecodeVerifiedDamaged(t *testing.T) {
+
+	e, err := NewEncoder(WithLengthHeader(), WithChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := noisyNRGBA(32, 32)
+	end, err := e.EncodeImage(img, "damaged beyond repair", Point{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The 9th pixel holds the first bit of the message itself.
+	img.Pix[img.PixOffset(8, 0)] ^= 1
+
+	_, result, err := e.DecodeImageVerified(img, Point{}, end)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("got error %v, want ErrChecksumMismatch", err)
+	}
+	if result.Valid || result.Checksum {
+		t.Fatalf("damaged message gave %+v", result)
+	}
+}
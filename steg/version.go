@@ -0,0 +1,202 @@
+package steg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+)
+
+const (
+	// formatVersion is stored in the top two bits of the flags
+	// byte of the format header.
+	formatVersion = 1
+
+	flagCompressed   = 1 << 0
+	flagEncrypted    = 1 << 1
+	flagChecksum     = 1 << 2
+	flagLengthHeader = 1 << 3
+	flagMetadata     = 1 << 4
+
+	// formatHeaderSize is the size in bytes of the format
+	// header: a flags byte and two bytes of layout.
+	formatHeaderSize = 3
+
+	// formatHeaderPixels is how many pixels the format header
+	// takes up, one bit to each.
+	formatHeaderPixels = formatHeaderSize * 8
+)
+
+/*
+SetFormatHeader specifies whether Encode writes a header before
+the message recording how it was encoded, so that Decode can
+configure itself to match rather than relying on the encoder's
+own settings. The header holds a format version and whether
+compression, encryption, checksums, metadata and the length
+header were used, followed by the channels in the order given to
+SetChannels, the bit depth and the msg bit. It is always written
+to the least significant bit of the red channel of the 24 pixels
+from start, whatever the encoder's channel settings, and the
+message follows from the next pixel.
+
+When decoding only the passphrase, if the message is encrypted,
+and settings the header doesn't record, such as the magic
+marker, traversal, bit order and scatter seed, need to match
+those used to encode. The format header cannot be combined with
+SetChannelBits, SetChroma, SetBitHopping, SetDensity or a null
+terminator, and FindStart cannot be used with it. It is disabled
+by default.
+*/
+func (e *Encoder) SetFormatHeader(enabled bool) {
+	e.formatHeader = enabled
+}
+
+func (e *Encoder) checkFormatHeader() error {
+	if !e.formatHeader {
+		return nil
+	}
+	if e.channelBits != nil || e.chroma || e.hopKey != "" || e.densityWindow != 0 || e.terminator {
+		return errors.New("format header cannot be combined with channel bits, chroma, bit hopping, density or a null terminator")
+	}
+	if e.bit > 7 {
+		return fmt.Errorf("format header cannot record msg bit %d, only 0-7", e.bit)
+	}
+	return nil
+}
+
+// formatPixels returns how many pixels the format header takes
+// up, which is zero when it is disabled.
+func (e *Encoder) formatPixels() int {
+	if !e.formatHeader {
+		return 0
+	}
+	return formatHeaderPixels
+}
+
+// bodyStart returns the point after the format header written
+// at start, or start itself if the format header is disabled.
+func (e *Encoder) bodyStart(bounds image.Rectangle, start Point) Point {
+	if !e.formatHeader {
+		return start
+	}
+	first := offsetFromMin(bounds, e.traversal, start)
+	return pointAt(bounds, e.traversal, first+formatHeaderPixels)
+}
+
+// formatHeaderBytes returns the format header describing e.
+func (e *Encoder) formatHeaderBytes() []byte {
+
+	flags := byte(formatVersion << 6)
+	if e.compression {
+		flags |= flagCompressed
+	}
+	if e.passphrase != "" {
+		flags |= flagEncrypted
+	}
+	if e.checksum {
+		flags |= flagChecksum
+	}
+	if e.lengthHeader {
+		flags |= flagLengthHeader
+	}
+	if e.metadata != nil {
+		flags |= flagMetadata
+	}
+
+	// The channels are packed two bits each after a two bit
+	// count, followed by the depth and msg bit.
+	cs := e.activeChannels()
+	var layout uint16 = uint16(len(cs) - 1)
+	for i, c := range cs {
+		layout |= uint16(c) << uint(2+2*i)
+	}
+	layout |= uint16(e.bitDepth()-1) << 10
+	layout |= uint16(e.bit) << 12
+
+	return []byte{flags, byte(layout >> 8), byte(layout)}
+}
+
+// formatHeaderEncoder returns the encoder the format header
+// itself is written and read with.
+func (e *Encoder) formatHeaderEncoder() *Encoder {
+	return &Encoder{traversal: e.traversal}
+}
+
+// writeFormatHeader writes the format header describing e to
+// the pixels from start.
+func (e *Encoder) writeFormatHeader(ctx context.Context, c carrier, start Point) (EncodeStats, error) {
+	bounds := c.bounds()
+	first := offsetFromMin(bounds, e.traversal, start)
+	return e.formatHeaderEncoder().writeMsg(ctx, c, formatHeaderPixels, func(i int) Point {
+		return pointAt(bounds, e.traversal, first+i)
+	}, e.formatHeaderBytes())
+}
+
+/*
+readFormatHeader reads the format header at start and returns a
+copy of e configured to match it, along with the point the
+message begins at. ErrNoMessage is returned if there is no
+format header at start.
+*/
+func (e *Encoder) readFormatHeader(ctx context.Context, c carrier, start Point) (*Encoder, Point, error) {
+
+	bounds := c.bounds()
+	if !inBounds(bounds, start) {
+		return nil, start, ErrStartOutOfBounds
+	}
+	first := offsetFromMin(bounds, e.traversal, start)
+	if bounds.Dx()*bounds.Dy()-first-1 < formatHeaderPixels {
+		return nil, start, fmt.Errorf("format header %w", ErrEndOutOfBounds)
+	}
+
+	b, err := e.formatHeaderEncoder().readMsg(ctx, c, formatHeaderPixels, func(i int) Point {
+		return pointAt(bounds, e.traversal, first+i)
+	})
+	if err != nil {
+		return nil, start, err
+	}
+
+	flags := b[0]
+	if v := flags >> 6; v != formatVersion {
+		return nil, start, fmt.Errorf("%w: format header has unknown version %d", ErrNoMessage, v)
+	}
+	if flags&0x20 != 0 {
+		return nil, start, fmt.Errorf("%w: format header has unknown flags", ErrNoMessage)
+	}
+
+	d := *e
+	d.formatHeader = false
+	d.compression = flags&flagCompressed != 0
+	d.checksum = flags&flagChecksum != 0
+	d.lengthHeader = flags&flagLengthHeader != 0
+
+	if flags&flagMetadata == 0 {
+		d.metadata = nil
+	} else if d.metadata == nil {
+		d.metadata = &Metadata{}
+	}
+
+	if flags&flagEncrypted == 0 {
+		d.passphrase = ""
+	} else if e.passphrase == "" {
+		return nil, start, errors.New("message is encrypted but the encoder has no passphrase; see SetPassphrase")
+	}
+
+	layout := uint16(b[1])<<8 | uint16(b[2])
+	n := int(layout&3) + 1
+	d.channels = make([]Channel, n)
+	for i := range d.channels {
+		d.channels[i] = Channel(layout >> uint(2+2*i) & 3)
+	}
+	d.depth = int(layout>>10&3) + 1
+	d.bit = int(layout >> 12 & 7)
+
+	if err := d.SetChannels(d.channels); err != nil {
+		return nil, start, fmt.Errorf("%w: format header: %w", ErrNoMessage, err)
+	}
+	if err := d.checkSettings(); err != nil {
+		return nil, start, fmt.Errorf("%w: format header: %w", ErrNoMessage, err)
+	}
+
+	return &d, pointAt(bounds, e.traversal, first+formatHeaderPixels), nil
+}
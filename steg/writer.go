@@ -0,0 +1,182 @@
+package steg
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+)
+
+/*
+MsgWriter is the writer returned by EncodeWriter. Bytes written
+to it make up the message, which is written to the image and
+saved to dst when it is closed.
+*/
+type MsgWriter struct {
+	e        *Encoder
+	src, dst string
+	start    Point
+	bounds   image.Rectangle
+	set      *pixelSet // pixels msg can be written to, if not all of them
+	msg      []byte
+	end      Point
+	closed   bool
+}
+
+/*
+EncodeWriter returns a writer that the message to be hidden in
+src can be written to a piece at a time, for instance as it
+arrives over a network, rather than being passed to Encode all
+at once. Closing the writer encodes the message and saves the
+image to dst as Encode would; the end point is then available
+from End.
+
+Nothing is written to the image until the writer is closed:
+the whole message is held in memory by the writer, since its
+length must be known before any of it can be framed, so
+EncodeWriter saves the caller from assembling the message but
+not from the memory it takes up. Write returns an error wrapping
+ErrMsgTooLarge as soon as the message no longer fits between
+start and the end of the image, counting only the pixels the
+encoder's settings let it write to. With density or skipping
+transparent pixels enabled (see SetDensity and
+SetSkipTransparent) this means src is decoded by EncodeWriter as
+well as by Close. When compression is enabled how large the
+message will be isn't known until it is compressed, so it is
+only checked by Close.
+
+EncodeWriter returns an error if src cannot be read, dst has an
+unsupported extension, start is outside the bounds of src or the
+encoder's settings are invalid.
+*/
+func (e *Encoder) EncodeWriter(src, dst string, start Point) (*MsgWriter, error) {
+
+	if err := e.checkSettings(); err != nil {
+		return nil, err
+	}
+
+	if _, err := e.encoderFor(dst); err != nil {
+		return nil, err
+	}
+
+	bounds, err := readBounds(src)
+	if err != nil {
+		return nil, err
+	}
+	if !inBounds(bounds, start) {
+		return nil, ErrStartOutOfBounds
+	}
+
+	// Which pixels density and transparency leave usable depends
+	// on the image itself.
+	var c carrier
+	if e.densityWindow != 0 || e.skipTransparent {
+		img, err := readImage(src)
+		if err != nil {
+			return nil, err
+		}
+		if c, err = e.carrierFor(fromJPEG(img)); err != nil {
+			return nil, err
+		}
+	}
+
+	return &MsgWriter{
+		e:      e,
+		src:    src,
+		dst:    dst,
+		start:  start,
+		bounds: bounds,
+		set:    e.pixels(c, bounds, e.bodyStart(bounds, start)),
+	}, nil
+}
+
+func (w *MsgWriter) Write(p []byte) (n int, err error) {
+
+	if w.closed {
+		return 0, errors.New("write to closed MsgWriter")
+	}
+
+	if !w.e.compression {
+		if err := w.fits(len(w.msg) + len(p)); err != nil {
+			return 0, err
+		}
+	}
+
+	w.msg = append(w.msg, p...)
+	return len(p), nil
+}
+
+// fits returns an error wrapping ErrMsgTooLarge if a message of
+// n bytes can't be written from w.start.
+func (w *MsgWriter) fits(n int) error {
+	size, hdr := w.e.framedSize(n)
+	pixels := w.e.payloadPixels(size, hdr)
+	if w.set != nil {
+		return checkSetFits(w.set, pixels)
+	}
+	if w.e.mirror {
+		pixels *= 2
+	}
+	return w.e.checkFits(w.bounds, w.start, w.e.formatPixels()+pixels)
+}
+
+/*
+Close writes the message to the image and saves it to dst. It
+returns the same errors Encode would, including ErrMsgEmpty if
+nothing was written. Calling Close more than once returns an
+error.
+*/
+func (w *MsgWriter) Close() error {
+
+	if w.closed {
+		return errors.New("MsgWriter already closed")
+	}
+	w.closed = true
+
+	end, err := w.e.encodeMsgFile(w.src, w.dst, w.start, w.msg, func(img draw.Image) (Point, error) {
+		stats, err := w.e.encodeImage(context.Background(), img, w.msg, w.start)
+		return stats.End, err
+	})
+	w.msg = nil
+	if err != nil {
+		return err
+	}
+
+	w.end = end
+	return nil
+}
+
+// End returns the point after the message once Close has
+// succeeded, to be passed to Decode.
+func (w *MsgWriter) End() Point {
+	return w.end
+}
+
+/*
+EncodeFrom is like Encode but reads the message from msg until
+io.EOF, for messages that come from an HTTP request body or some
+other stream. As with EncodeWriter the message is buffered until
+it has been read in full, and reading stops with an error
+wrapping ErrMsgTooLarge as soon as what has been read no longer
+fits, so msg is never read far past what the image can hold.
+Errors from reading msg are returned as is and leave dst
+unwritten.
+*/
+func (e *Encoder) EncodeFrom(src, dst string, msg io.Reader, start Point) (end Point, err error) {
+
+	w, err := e.EncodeWriter(src, dst, start)
+	if err != nil {
+		return end, err
+	}
+
+	if _, err = io.Copy(w, msg); err != nil {
+		return end, err
+	}
+
+	if err = w.Close(); err != nil {
+		return end, err
+	}
+
+	return w.End(), nil
+}
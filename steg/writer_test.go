@@ -0,0 +1,125 @@
+package steg
+
+import (
+	"errors"
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestEncodeWriter(t *testing.T) {
+
+	e, err := NewEncoder(WithLengthHeader(), WithChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := writePNG(t, noisyNRGBA(32, 32))
+	dst := dstPath(t, ".png")
+
+	w, err := e.EncodeWriter(src, dst, Point{3, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := []string{"written ", "a piece ", "at a time"}
+	for _, p := range parts {
+		if _, err = w.Write([]byte(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err == nil {
+		t.Fatal("second Close succeeded")
+	}
+	if _, err = w.Write([]byte("x")); err == nil {
+		t.Fatal("Write after Close succeeded")
+	}
+
+	got, err := e.Decode(dst, Point{3, 0}, w.End())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Join(parts, ""); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+// Whatever Write accepts must fit when the writer is closed, with
+// every setting that leaves some pixels unused.
+func TestEncodeWriterFillsImage(t *testing.T) {
+
+	// Flat on top for density and transparent on the left for
+	// skipping transparent pixels, noise elsewhere.
+	img := noisyNRGBA(40, 40)
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 40; x++ {
+			i := img.PixOffset(x, y)
+			if y < 15 {
+				copy(img.Pix[i:i+3], []byte{0x80, 0x80, 0x80})
+			}
+			if x < 12 {
+				img.Pix[i+3] = 0
+			}
+		}
+	}
+	src := writePNG(t, img)
+
+	for _, opts := range [][]Option{
+		{WithLengthHeader()},
+		{WithLengthHeader(), WithDensity(10, 3)},
+		{WithLengthHeader(), WithSkipTransparent()},
+		{WithLengthHeader(), WithPixelStride(3, 1)},
+		{WithLengthHeader(), WithPixelStride(2, 0), WithSkipTransparent()},
+		{WithLengthHeader(), WithChecksum(), WithMirror()},
+	} {
+
+		e, err := NewEncoder(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dst := dstPath(t, ".png")
+		start := Point{1, 2}
+		w, err := e.EncodeWriter(src, dst, start)
+		if err != nil {
+			t.Fatalf("%s: %v", e, err)
+		}
+
+		var msg []byte
+		for {
+			if _, err := w.Write([]byte{'a' + byte(len(msg)%26)}); err != nil {
+				if !errors.Is(err, ErrMsgTooLarge) {
+					t.Fatalf("%s: got error %v, want ErrMsgTooLarge", e, err)
+				}
+				break
+			}
+			msg = append(msg, 'a'+byte(len(msg)%26))
+		}
+
+		if err = w.Close(); err != nil {
+			t.Fatalf("%s: %d bytes accepted by Write: %v", e, len(msg), err)
+		}
+
+		got, err := e.DecodeAuto(dst, start)
+		if err != nil {
+			t.Fatalf("%s: %v", e, err)
+		}
+		if got != string(msg) {
+			t.Fatalf("%s: decoded %d bytes, want %d", e, len(got), len(msg))
+		}
+
+		// Write stops at the true capacity rather than short of it.
+		if _, err = e.EncodeImage(copyNRGBA(img), string(msg)+"z", start); !errors.Is(err, ErrMsgTooLarge) {
+			t.Fatalf("%s: Write refused byte %d, which EncodeImage accepts", e, len(msg)+1)
+		}
+	}
+}
+
+// copyNRGBA returns a copy of img.
+func copyNRGBA(img *image.NRGBA) *image.NRGBA {
+	c := *img
+	c.Pix = append([]byte(nil), img.Pix...)
+	return &c
+}